@@ -0,0 +1,41 @@
+// Package api holds the JSON bodies of the hdckit HTTP API, shared by the
+// server (package server) and its client (package remote).
+package api
+
+// ShellRequest is the body of POST shell.
+type ShellRequest struct {
+	Cmd string `json:"cmd"`
+}
+
+// ShellResponse is the reply of POST shell.
+type ShellResponse struct {
+	Output string `json:"output"`
+}
+
+// ForwardRequest is the body of POST forward.
+type ForwardRequest struct {
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// UninstallRequest is the body of POST uninstall.
+type UninstallRequest struct {
+	Bundle string `json:"bundle"`
+}
+
+// PointRequest is the body of the single point ui operations.
+type PointRequest struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// NodeResponse is the reply of POST ui/find.
+type NodeResponse struct {
+	Bounds     [4]int            `json:"bounds"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ErrorBody is the JSON body of a failed request.
+type ErrorBody struct {
+	Error string `json:"error"`
+}
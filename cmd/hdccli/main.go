@@ -4,12 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/airhandsome/hdckit-go/dashboard"
 	hdc "github.com/airhandsome/hdckit-go/hdc"
+	"github.com/airhandsome/hdckit-go/perf"
+	"github.com/airhandsome/hdckit-go/server"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +28,13 @@ var (
 	port  int
 	bin   string
 	debug bool
+
+	rpcTimeout time.Duration
+	displayID  int
+
+	allTargets bool
+	targetList []string
+	parallel   int
 )
 
 func main() {
@@ -50,16 +67,48 @@ hdccli uninstall com.example.app
 # Hilog（清空后查看）
 hdccli hilog --clear
 
+# 截图
+hdccli screenshot -o screen.png
+
+# 多设备并发（--all 或 --targets a,b,c；输出带设备序列号前缀）
+hdccli --all shell "param get const.product.model"
+hdccli --targets a,b install ./app.hap
+
 # UiDriver 示例
 hdccli ui size
 hdccli ui capture
-hdccli ui input "hello"`}
+hdccli ui stream --listen :8080
+hdccli ui record --out run.avi --duration 60s
+hdccli ui input "hello"
+
+# 布局导出（截图 + 布局 JSON + HTML 查看器 + 边框 PNG）
+hdccli ui dump --out dump
+
+# YAML 场景测试（截图与 JUnit 报告输出到 report/）
+hdccli run login.yaml --out report
+
+# 应用性能采样（CPU、PSS、线程数、帧率）
+hdccli perf com.example.app --interval 1s --duration 60s --csv perf.csv
+
+# 电池与温度记录（长时间运行）
+hdccli power --interval 30s --csv power.csv
+
+# 远程控制服务（HTTP JSON API）
+hdccli serve --listen :9700 --token secret
+
+# 网页控制台（设备列表、实时画面、hilog、布局检查）
+hdccli dashboard`}
 	root.PersistentFlags().StringVar(&host, "host", "127.0.0.1", "hdc host")
 	root.PersistentFlags().IntVar(&port, "port", 8710, "hdc port")
 	root.PersistentFlags().StringVar(&bin, "bin", "hdc", "hdc binary path")
 	root.PersistentFlags().BoolVar(&debug, "debug", true, "enable debug logs")
+	root.PersistentFlags().DurationVar(&rpcTimeout, "rpc-timeout", 3*time.Second, "timeout of a single uitest RPC")
+	root.PersistentFlags().IntVar(&displayID, "display", 0, "display id for ui commands and screenshots (0 = default)")
+	root.PersistentFlags().BoolVar(&allTargets, "all", false, "run shell, install, file send and screenshot on every connected device")
+	root.PersistentFlags().StringSliceVar(&targetList, "targets", nil, "run shell, install, file send and screenshot on these devices (a,b,c)")
+	root.PersistentFlags().IntVar(&parallel, "parallel", 4, "max devices handled at once with --all/--targets")
 
-	root.AddCommand(cmdList(), cmdTrack(), cmdShell(), cmdForward(), cmdReverse(), cmdFile(), cmdInstall(), cmdUninstall(), cmdHilog(), cmdUi())
+	root.AddCommand(cmdList(), cmdTrack(), cmdShell(), cmdForward(), cmdReverse(), cmdFile(), cmdInstall(), cmdUninstall(), cmdHilog(), cmdUi(), cmdScreenshot(), cmdRun(), cmdServe(), cmdDashboard(), cmdPerf(), cmdPower())
 
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -71,6 +120,39 @@ func client() *hdc.Client {
 	return hdc.NewClient(hdc.Options{Host: host, Port: port, Bin: bin, Debug: debug})
 }
 
+func uiDriver(target string) *hdc.UiDriver {
+	drv := client().Target(target).CreateUiDriverWithOptions(hdc.UiDriverOptions{Timeout: rpcTimeout})
+	drv.SetDisplay(displayID)
+	return drv
+}
+
+// deviceGroup returns the devices picked by --all or --targets, nil when neither is set.
+func deviceGroup(ctx context.Context) (*hdc.DeviceGroup, error) {
+	var g *hdc.DeviceGroup
+	switch {
+	case allTargets:
+		var err error
+		if g, err = client().GroupAll(ctx); err != nil {
+			return nil, err
+		}
+	case len(targetList) > 0:
+		g = client().Group(targetList...)
+	default:
+		return nil, nil
+	}
+	g.Parallel = parallel
+	return g, nil
+}
+
+// printGroup prints the per-device output and fails if any device failed.
+func printGroup(res hdc.GroupResult) error {
+	res.Print(os.Stdout)
+	if failed := res.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed on %d/%d devices: %s", len(failed), len(res), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 func singleTargetOrErr(ctx context.Context) (string, error) {
 	ts, err := client().ListTargets(ctx)
 	if err != nil {
@@ -117,7 +199,13 @@ func cmdTrack() *cobra.Command {
 }
 
 func cmdShell() *cobra.Command {
-	return &cobra.Command{Use: "shell [target] <cmd>", Args: cobra.MinimumNArgs(1), Short: "Run shell on target", Example: "hdccli shell \"echo hello\"\nhdccli shell <target> \"echo hello\"", RunE: func(cmd *cobra.Command, args []string) error {
+	return &cobra.Command{Use: "shell [target] <cmd>", Args: cobra.MinimumNArgs(1), Short: "Run shell on target", Example: "hdccli shell \"echo hello\"\nhdccli shell <target> \"echo hello\"\nhdccli --all shell \"param get const.product.model\"", RunE: func(cmd *cobra.Command, args []string) error {
+		if g, err := deviceGroup(context.Background()); err != nil || g != nil {
+			if err != nil {
+				return err
+			}
+			return printGroup(g.Shell(context.Background(), join(args)))
+		}
 		var target string
 		var command []string
 		if len(args) >= 2 {
@@ -352,7 +440,13 @@ func cmdFile() *cobra.Command {
 		}
 		return client().Target(target).RecvFile(context.Background(), remote, local)
 	}}
-	send := &cobra.Command{Use: "send [target] <local> <remote>", Args: cobra.MinimumNArgs(2), Example: "hdccli file send ./a.txt /data/local/tmp/a.txt", RunE: func(cmd *cobra.Command, args []string) error {
+	send := &cobra.Command{Use: "send [target] <local> <remote>", Args: cobra.MinimumNArgs(2), Example: "hdccli file send ./a.txt /data/local/tmp/a.txt\nhdccli --all file send ./a.txt /data/local/tmp/a.txt", RunE: func(cmd *cobra.Command, args []string) error {
+		if g, err := deviceGroup(context.Background()); err != nil || g != nil {
+			if err != nil {
+				return err
+			}
+			return printGroup(g.SendFile(context.Background(), args[len(args)-2], args[len(args)-1]))
+		}
 		var target, local, remote string
 		if len(args) == 3 {
 			target, local, remote = args[0], args[1], args[2]
@@ -373,7 +467,13 @@ func cmdFile() *cobra.Command {
 }
 
 func cmdInstall() *cobra.Command {
-	return &cobra.Command{Use: "install [target] <hap>", Args: cobra.MinimumNArgs(1), Short: "Install hap", Example: "hdccli install ./app.hap", RunE: func(cmd *cobra.Command, args []string) error {
+	return &cobra.Command{Use: "install [target] <hap>", Args: cobra.MinimumNArgs(1), Short: "Install hap", Example: "hdccli install ./app.hap\nhdccli --targets a,b install ./app.hap", RunE: func(cmd *cobra.Command, args []string) error {
+		if g, err := deviceGroup(context.Background()); err != nil || g != nil {
+			if err != nil {
+				return err
+			}
+			return printGroup(g.Install(context.Background(), args[len(args)-1]))
+		}
 		var target, hap string
 		if len(args) == 2 {
 			target, hap = args[0], args[1]
@@ -448,7 +548,7 @@ func cmdUi() *cobra.Command {
 				return err
 			}
 		}
-		drv := client().Target(target).CreateUiDriver()
+		drv := uiDriver(target)
 		if err := drv.Start(context.Background()); err != nil {
 			return err
 		}
@@ -487,7 +587,7 @@ func cmdUi() *cobra.Command {
 			return err
 		}
 		var saved atomic.Int64
-		drv := client().Target(target).CreateUiDriver()
+		drv := uiDriver(target)
 		if err := drv.Start(context.Background()); err != nil {
 			return err
 		}
@@ -518,7 +618,33 @@ func cmdUi() *cobra.Command {
 	capture.Flags().StringVar(&outDir, "out", "frames", "output directory for frames")
 	capture.Flags().IntVar(&maxCount, "count", 10, "max frames to save")
 	capture.Flags().IntVar(&timeoutSec, "timeout", 30, "max seconds to wait for frames")
-	input := &cobra.Command{Use: "input [target] <text>", Args: cobra.MinimumNArgs(1), Example: "hdccli ui input \"hello\"", RunE: func(cmd *cobra.Command, args []string) error {
+	var dumpDir string
+	dump := &cobra.Command{Use: "dump [target]", Short: "Save screenshot, layout JSON and an HTML bounds viewer", Args: cobra.MaximumNArgs(1), Example: "hdccli ui dump --out dump", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) >= 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		drv := uiDriver(target)
+		if err := drv.Start(context.Background()); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		if err := hdc.DumpUI(context.Background(), drv, dumpDir); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "saved screen.png, layout.json, layout.html and bounds.png to %s\n", dumpDir)
+		return nil
+	}}
+	dump.Flags().StringVar(&dumpDir, "out", "dump", "output directory")
+	var inX, inY int
+	var inClear, inAppend, inPaste bool
+	input := &cobra.Command{Use: "input [target] <text>", Args: cobra.MinimumNArgs(1), Example: "hdccli ui input \"hello\"\nhdccli ui input --x 300 --y 800 --clear \"你好\"", RunE: func(cmd *cobra.Command, args []string) error {
 		var target, text string
 		if len(args) == 2 {
 			target, text = args[0], args[1]
@@ -532,17 +658,307 @@ func cmdUi() *cobra.Command {
 		} else {
 			return cmd.Usage()
 		}
-		drv := client().Target(target).CreateUiDriver()
+		drv := uiDriver(target)
+		if err := drv.Start(context.Background()); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		opts := hdc.InputTextOptions{Clear: inClear, Append: inAppend, Paste: inPaste}
+		// without coordinates type into the focused component
+		if cmd.Flags().Changed("x") || cmd.Flags().Changed("y") {
+			opts.At = &image.Point{X: inX, Y: inY}
+		}
+		return drv.InputTextWith(context.Background(), text, opts)
+	}}
+	input.Flags().IntVar(&inX, "x", 0, "tap x before typing (default: focused component)")
+	input.Flags().IntVar(&inY, "y", 0, "tap y before typing (default: focused component)")
+	input.Flags().BoolVar(&inClear, "clear", false, "clear existing text first")
+	input.Flags().BoolVar(&inAppend, "append", false, "append to existing text")
+	input.Flags().BoolVar(&inPaste, "paste", false, "paste through the clipboard instead of typing")
+	rotate := &cobra.Command{Use: "rotate [target] <0|90|180|270>", Args: cobra.RangeArgs(1, 2), Example: "hdccli ui rotate 90", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) == 2 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		r, err := hdc.ParseRotation(args[len(args)-1])
+		if err != nil {
+			return err
+		}
+		drv := uiDriver(target)
+		if err := drv.Start(context.Background()); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		return drv.SetDisplayRotation(context.Background(), r)
+	}}
+	wake := &cobra.Command{Use: "wake [target]", Args: cobra.MaximumNArgs(1), Example: "hdccli ui wake", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) == 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		drv := uiDriver(target)
+		if err := drv.Start(context.Background()); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		return drv.WakeUpDisplay(context.Background())
+	}}
+	var pin string
+	unlock := &cobra.Command{Use: "unlock [target]", Args: cobra.MaximumNArgs(1), Example: "hdccli ui unlock --pin 123456", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) == 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		drv := uiDriver(target)
 		if err := drv.Start(context.Background()); err != nil {
 			return err
 		}
 		defer drv.Stop()
-		return drv.InputText(context.Background(), text, 0, 0)
+		return drv.Unlock(context.Background(), pin)
+	}}
+	unlock.Flags().StringVar(&pin, "pin", "", "lock screen PIN to type after the swipe")
+	var listen string
+	var fps int
+	var scale float64
+	stream := &cobra.Command{Use: "stream [target]", Args: cobra.MinimumNArgs(0), Example: "hdccli ui stream --listen :8080 --fps 15", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) >= 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		drv := uiDriver(target)
+		if err := drv.Start(ctx); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		streamer := hdc.NewMJPEGStreamer(fps)
+		defer streamer.Close()
+		sess, err := drv.StartCaptureScreen(ctx, streamer.Publish, scale)
+		if err != nil {
+			return err
+		}
+		defer sess.Stop(context.Background())
+		srv := &http.Server{Addr: listen, Handler: streamer}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		fmt.Fprintf(os.Stderr, "streaming %s on http://%s/ (Ctrl-C to stop)\n", target, listen)
+		select {
+		case <-ctx.Done():
+		case err := <-errc:
+			return err
+		}
+		streamer.Close()
+		sctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		return srv.Shutdown(sctx)
+	}}
+	stream.Flags().StringVar(&listen, "listen", ":8080", "http listen address")
+	stream.Flags().IntVar(&fps, "fps", 15, "max frames per second per viewer (0 = unlimited)")
+	stream.Flags().Float64Var(&scale, "scale", 0, "capture scale in (0,1); 0 = full size")
+	var recOut string
+	var recDuration time.Duration
+	var recFps int
+	var recScale float64
+	record := &cobra.Command{Use: "record [target]", Args: cobra.MinimumNArgs(0), Example: "hdccli ui record --out run.avi --duration 60s", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) >= 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		drv := uiDriver(target)
+		if err := drv.Start(ctx); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		rec, err := drv.StartRecording(ctx, recOut, hdc.RecordOptions{FPS: recFps, Scale: recScale})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "recording %s to %s (Ctrl-C to stop)\n", target, recOut)
+		if recDuration > 0 {
+			t := time.NewTimer(recDuration)
+			defer t.Stop()
+			select {
+			case <-ctx.Done():
+			case <-t.C:
+			}
+		} else {
+			<-ctx.Done()
+		}
+		if err := rec.Stop(context.Background()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "saved %s (%d frames, %d dropped)\n", recOut, rec.Frames(), rec.Dropped())
+		return nil
+	}}
+	record.Flags().StringVar(&recOut, "out", "record.avi", "output AVI file")
+	record.Flags().DurationVar(&recDuration, "duration", 60*time.Second, "recording length (0 = until Ctrl-C)")
+	record.Flags().IntVar(&recFps, "fps", 30, "video timeline frame rate")
+	record.Flags().Float64Var(&recScale, "scale", 0, "capture scale in (0,1); 0 = full size")
+	script := &cobra.Command{Use: "script", Short: "Record and replay UI scripts"}
+	var scriptOut string
+	scriptRecord := &cobra.Command{Use: "record [target]", Args: cobra.MaximumNArgs(1), Example: "hdccli ui script record --out login.yaml", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) == 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		drv := uiDriver(target)
+		fmt.Fprintf(os.Stderr, "recording actions on %s to %s (Ctrl-C to stop)\n", target, scriptOut)
+		s, err := drv.RecordDeviceActions(ctx, func(st hdc.Step) { fmt.Println(st) })
+		if s == nil {
+			return err
+		}
+		if serr := s.Save(scriptOut); serr != nil {
+			return serr
+		}
+		fmt.Fprintf(os.Stderr, "saved %s (%d steps)\n", scriptOut, len(s.Steps))
+		return err
+	}}
+	scriptRecord.Flags().StringVar(&scriptOut, "out", "script.yaml", "output script (.json for JSON, YAML otherwise)")
+	var playSpeed float64
+	var playNoDelay bool
+	scriptPlay := &cobra.Command{Use: "play <file> [target]", Args: cobra.RangeArgs(1, 2), Example: "hdccli ui script play login.yaml --speed 2", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := hdc.LoadScript(args[0])
+		if err != nil {
+			return err
+		}
+		var target string
+		if len(args) == 2 {
+			target = args[1]
+		} else {
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		drv := uiDriver(target)
+		if err := drv.Start(ctx); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		return drv.PlayScript(ctx, s, hdc.PlayOptions{Speed: playSpeed, NoDelay: playNoDelay, OnStep: func(i int, st hdc.Step) {
+			fmt.Printf("[%d/%d] %s\n", i+1, len(s.Steps), st)
+		}})
 	}}
-	ui.AddCommand(size, capture, input)
+	scriptPlay.Flags().Float64Var(&playSpeed, "speed", 1, "delay scale; 2 plays twice as fast")
+	scriptPlay.Flags().BoolVar(&playNoDelay, "no-delay", false, "skip the recorded delays")
+	script.AddCommand(scriptRecord, scriptPlay)
+	ui.AddCommand(size, capture, dump, input, rotate, wake, unlock, stream, record, script)
 	return ui
 }
 
+func cmdScreenshot() *cobra.Command {
+	var out, region string
+	var quality int
+	c := &cobra.Command{Use: "screenshot [target]", Args: cobra.MinimumNArgs(0), Short: "Take a screenshot", Example: "hdccli screenshot -o screen.png\nhdccli screenshot -o button.jpg --region 100,200,300,120\nhdccli --all screenshot -o shots/screen.png  # shots/screen-<serial>.png\nhdccli --all screenshot -o shots/  # shots/<serial>.png", RunE: func(cmd *cobra.Command, args []string) error {
+		g, err := deviceGroup(context.Background())
+		if err != nil {
+			return err
+		}
+		var target string
+		if len(args) >= 1 {
+			target = args[0]
+		} else if g == nil {
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		opts := hdc.ScreenshotOptions{Format: "png", Quality: quality}
+		if ext := strings.ToLower(filepath.Ext(out)); ext == ".jpg" || ext == ".jpeg" {
+			opts.Format = "jpeg"
+		}
+		if region != "" {
+			r, err := parseRegion(region)
+			if err != nil {
+				return err
+			}
+			opts.Region = r
+		}
+		if g != nil {
+			g.NewDriver = func(t *hdc.Target) *hdc.UiDriver { return uiDriver(t.Key()) }
+			return printGroup(g.Screenshot(context.Background(), out, opts))
+		}
+		drv := uiDriver(target)
+		if err := drv.Start(context.Background()); err != nil {
+			return err
+		}
+		defer drv.Stop()
+		b, err := drv.Screenshot(context.Background(), opts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, b, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "saved %s (%d bytes)\n", out, len(b))
+		return nil
+	}}
+	c.Flags().StringVarP(&out, "out", "o", "screenshot.png", "output file (.png or .jpg)")
+	c.Flags().StringVar(&region, "region", "", "crop region as x,y,w,h")
+	c.Flags().IntVar(&quality, "quality", 90, "jpeg quality")
+	return c
+}
+
+// parseRegion parses "x,y,w,h".
+func parseRegion(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("invalid region %q, want x,y,w,h", s)
+	}
+	v := make([]int, 4)
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("invalid region %q: %w", s, err)
+		}
+		v[i] = n
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
+
 func detectImageExt(b []byte) string {
 	if len(b) >= 8 && b[0] == 0x89 && b[1] == 0x50 && b[2] == 0x4E && b[3] == 0x47 {
 		return "png"
@@ -563,3 +979,293 @@ func join(ss []string) string {
 	}
 	return out
 }
+
+func cmdRun() *cobra.Command {
+	var target, out, junit string
+	var timeout time.Duration
+	var noShots bool
+	run := &cobra.Command{Use: "run <scenario.yaml>...", Short: "Run YAML test scenarios", Args: cobra.MinimumNArgs(1), Example: "hdccli run login.yaml --out report --junit report/junit.xml", RunE: func(cmd *cobra.Command, args []string) error {
+		var scenarios []*hdc.Scenario
+		for _, p := range args {
+			sc, err := hdc.LoadScenario(p)
+			if err != nil {
+				return err
+			}
+			scenarios = append(scenarios, sc)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		var results []*hdc.ScenarioResult
+		for _, sc := range scenarios {
+			t := target
+			if t == "" {
+				t = sc.Target
+			}
+			if t == "" {
+				var err error
+				if t, err = singleTargetOrErr(ctx); err != nil {
+					return err
+				}
+			}
+			drv := uiDriver(t)
+			if err := drv.Start(ctx); err != nil {
+				return err
+			}
+			fmt.Printf("=== %s on %s\n", sc.Name, t)
+			res := drv.RunScenario(ctx, sc, hdc.ScenarioOptions{OutDir: filepath.Join(out, sc.Name), Timeout: timeout, NoStepScreenshots: noShots, OnStep: func(r hdc.StepResult) {
+				status := "ok"
+				if r.Err != nil {
+					status = "FAIL: " + r.Err.Error()
+				}
+				fmt.Printf("[%02d] %s (%s) %s\n", r.Index+1, r.Name, r.Duration.Round(time.Millisecond), status)
+			}})
+			drv.Stop()
+			results = append(results, res)
+		}
+		if junit == "" {
+			junit = filepath.Join(out, "junit.xml")
+		}
+		if err := hdc.WriteJUnitFile(junit, results...); err != nil {
+			return err
+		}
+		fmt.Printf("report: %s\n", junit)
+		var failed []string
+		for _, r := range results {
+			if r.Failed() {
+				failed = append(failed, r.Name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed scenarios: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	}}
+	run.Flags().StringVar(&target, "target", "", "device key (default: scenario target or the only device)")
+	run.Flags().StringVar(&out, "out", "report", "directory for step screenshots and pulled files")
+	run.Flags().StringVar(&junit, "junit", "", "JUnit XML report path (default <out>/junit.xml)")
+	run.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "default wait for a step's component")
+	run.Flags().BoolVar(&noShots, "no-step-screenshots", false, "skip the screenshot after every step")
+	return run
+}
+
+func cmdServe() *cobra.Command {
+	var listen, token string
+	serve := &cobra.Command{Use: "serve", Short: "Serve devices over an HTTP JSON API", Args: cobra.NoArgs, Example: "hdccli serve --listen :9700 --token secret", RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkListen(listen, token); err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		api := server.New(server.Options{Client: client(), Driver: hdc.UiDriverOptions{Timeout: rpcTimeout}, Token: token})
+		defer api.Close()
+		mux := http.NewServeMux()
+		mux.Handle("/api/", api)
+		srv := &http.Server{Addr: listen, Handler: mux}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		fmt.Fprintf(os.Stderr, "serving devices on http://%s/api/targets (Ctrl-C to stop)\n", listen)
+		select {
+		case <-ctx.Done():
+		case err := <-errc:
+			return err
+		}
+		sctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			// hilog and capture streams do not end on their own
+			return srv.Close()
+		}
+		return nil
+	}}
+	serve.Flags().StringVar(&listen, "listen", "127.0.0.1:9700", "http listen address; other than loopback requires --token")
+	serve.Flags().StringVar(&token, "token", "", "require this bearer token on every request (?token= on hilog and capture)")
+	return serve
+}
+
+func cmdDashboard() *cobra.Command {
+	var listen, token string
+	dash := &cobra.Command{Use: "dashboard", Short: "Serve a web dashboard of connected devices", Args: cobra.NoArgs, Example: "hdccli dashboard", RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkListen(listen, token); err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		d := dashboard.New(dashboard.Options{Client: client(), Driver: hdc.UiDriverOptions{Timeout: rpcTimeout}, Token: token})
+		defer d.Close()
+		go func() {
+			if err := d.Run(ctx); err != nil && ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, "track devices:", err)
+			}
+		}()
+		srv := &http.Server{Addr: listen, Handler: d}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		page := "http://" + listen + "/"
+		if token != "" {
+			page += "?token=" + token
+		}
+		fmt.Fprintf(os.Stderr, "dashboard on %s (Ctrl-C to stop)\n", page)
+		select {
+		case <-ctx.Done():
+		case err := <-errc:
+			return err
+		}
+		sctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			return srv.Close()
+		}
+		return nil
+	}}
+	dash.Flags().StringVar(&listen, "listen", "127.0.0.1:9800", "http listen address; other than loopback requires --token")
+	dash.Flags().StringVar(&token, "token", "", "require this token; open the page as /?token=<token>")
+	return dash
+}
+
+func cmdPerf() *cobra.Command {
+	var interval, duration time.Duration
+	var layer, csvOut, jsonOut string
+	var noMem, noFPS bool
+	c := &cobra.Command{Use: "perf <bundle> [target]", Short: "Sample CPU, memory, threads and FPS of an app", Args: cobra.RangeArgs(1, 2), Example: "hdccli perf com.example.app --interval 1s --duration 60s --csv perf.csv", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) == 2 {
+			target = args[1]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+		m, err := perf.Start(ctx, client().Target(target), args[0], perf.Options{
+			Interval: interval,
+			Layer:    layer,
+			NoMemory: noMem,
+			NoFPS:    noFPS,
+			OnSample: func(s perf.Sample) { fmt.Println(s) },
+		})
+		if err != nil {
+			return err
+		}
+		<-ctx.Done()
+		series := m.Stop()
+		if err := m.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, "some samples failed:", err)
+		}
+		for _, out := range []struct {
+			path  string
+			write func(io.Writer) error
+		}{{csvOut, series.WriteCSV}, {jsonOut, series.WriteJSON}} {
+			if out.path == "" {
+				continue
+			}
+			f, err := os.Create(out.path)
+			if err != nil {
+				return err
+			}
+			if err := out.write(f); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "saved %d samples to %s\n", len(series.Samples), out.path)
+		}
+		return nil
+	}}
+	c.Flags().DurationVar(&interval, "interval", time.Second, "time between samples")
+	c.Flags().DurationVar(&duration, "duration", 0, "stop after this long (default: until Ctrl-C)")
+	c.Flags().StringVar(&layer, "layer", "", "RenderService surface to count frames of (default: whole screen)")
+	c.Flags().StringVar(&csvOut, "csv", "", "write the samples as CSV")
+	c.Flags().StringVar(&jsonOut, "json", "", "write the samples as JSON")
+	c.Flags().BoolVar(&noMem, "no-mem", false, "skip hidumper --mem (faster samples)")
+	c.Flags().BoolVar(&noFPS, "no-fps", false, "skip the frame rate")
+	return c
+}
+
+func cmdPower() *cobra.Command {
+	var interval, duration time.Duration
+	var csvOut, jsonOut string
+	c := &cobra.Command{Use: "power [target]", Short: "Record battery and thermal state over time", Args: cobra.MaximumNArgs(1), Example: "hdccli power --interval 30s --csv power.csv", RunE: func(cmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) >= 1 {
+			target = args[0]
+		} else {
+			var err error
+			target, err = singleTargetOrErr(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+		m, err := perf.StartPower(ctx, client().Target(target), interval, func(s perf.PowerSample) {
+			hot := s.Thermal.Max()
+			fmt.Printf("%s battery=%d%% %s %.1f°C %.3fV thermal-level=%d hottest=%s %.1f°C %s\n", s.Time.Format("15:04:05"),
+				s.Battery.Level, s.Battery.Charge, s.Battery.Temperature, s.Battery.Voltage, s.Thermal.Level, hot.Name, hot.Temperature, s.Err)
+		})
+		if err != nil {
+			return err
+		}
+		<-ctx.Done()
+		samples := m.Stop()
+		for _, out := range []struct {
+			path  string
+			write func(io.Writer, []perf.PowerSample) error
+		}{{csvOut, perf.WritePowerCSV}, {jsonOut, perf.WritePowerJSON}} {
+			if out.path == "" {
+				continue
+			}
+			f, err := os.Create(out.path)
+			if err != nil {
+				return err
+			}
+			if err := out.write(f, samples); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "saved %d samples to %s\n", len(samples), out.path)
+		}
+		return nil
+	}}
+	c.Flags().DurationVar(&interval, "interval", 30*time.Second, "time between samples")
+	c.Flags().DurationVar(&duration, "duration", 0, "stop after this long (default: until Ctrl-C)")
+	c.Flags().StringVar(&csvOut, "csv", "", "write the samples as CSV")
+	c.Flags().StringVar(&jsonOut, "json", "", "write the samples as JSON")
+	return c
+}
+
+// checkListen refuses to expose shell, file and install access beyond this
+// machine without a token.
+func checkListen(addr, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("listen address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s without --token: anyone on the network could run shell commands on the devices", addr)
+}
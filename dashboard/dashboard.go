@@ -0,0 +1,193 @@
+// Package dashboard serves a small web UI for the devices of a lab host: a
+// device list with model, OS, battery, temperature and state, a live screen
+// that forwards clicks as taps, a hilog tail and a layout inspector. Device
+// access goes through the API of package server, mounted under /api/.
+//
+//	dash := dashboard.New(dashboard.Options{Client: c})
+//	defer dash.Close()
+//	go dash.Run(ctx)
+//	http.ListenAndServe(":9800", dash)
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+	"github.com/airhandsome/hdckit-go/perf"
+	"github.com/airhandsome/hdckit-go/server"
+)
+
+//go:embed static
+var static embed.FS
+
+// Options configures a Dashboard.
+type Options struct {
+	Client *hdc.Client
+	// Driver configures the UiDriver of each device.
+	Driver hdc.UiDriverOptions
+	// Token, if set, protects the API like server.Options.Token; open the page
+	// as /?token=<token> and it sends the token in headers from then on.
+	Token string
+	// Refresh is how often battery and thermal state are re-read (default 30s).
+	Refresh time.Duration
+}
+
+// Device is one row of the device list.
+type Device struct {
+	hdc.DeviceInfo
+	// State is "online" or "offline" (seen before, now gone).
+	State string `json:"state"`
+	// Battery and Thermal are nil until first read.
+	Battery   *perf.BatteryInfo `json:"battery,omitempty"`
+	Thermal   *perf.ThermalInfo `json:"thermal,omitempty"`
+	Connected time.Time         `json:"connected"`
+}
+
+// Dashboard is an http.Handler serving the page, /devices and the /api/ routes.
+type Dashboard struct {
+	opts Options
+	api  *server.Server
+	mux  *http.ServeMux
+
+	mu      sync.Mutex
+	devices map[string]*Device
+}
+
+// New returns a dashboard for opts.Client; call Run to start tracking devices.
+func New(opts Options) *Dashboard {
+	if opts.Client == nil {
+		opts.Client = hdc.NewClient(hdc.Options{})
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = 30 * time.Second
+	}
+	d := &Dashboard{
+		opts:    opts,
+		api:     server.New(server.Options{Client: opts.Client, Driver: opts.Driver, Token: opts.Token}),
+		mux:     http.NewServeMux(),
+		devices: map[string]*Device{},
+	}
+	sub, _ := fs.Sub(static, "static")
+	d.mux.Handle("/", http.FileServer(http.FS(sub)))
+	d.mux.Handle("/api/", d.api)
+	d.mux.HandleFunc("/devices", d.serveDevices)
+	return d
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) { d.mux.ServeHTTP(w, r) }
+
+// Close stops the UiDrivers started for the page.
+func (d *Dashboard) Close() { d.api.Close() }
+
+// Run tracks devices until ctx ends, loading the info of each new device and
+// refreshing battery and thermal state every Options.Refresh.
+func (d *Dashboard) Run(ctx context.Context) error {
+	tr, err := d.opts.Client.TrackTargets(ctx)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+	ticker := time.NewTicker(d.opts.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s := <-tr.Added():
+			d.mu.Lock()
+			d.devices[s] = &Device{DeviceInfo: hdc.DeviceInfo{Serial: s}, State: "online", Connected: time.Now()}
+			d.mu.Unlock()
+			go d.load(ctx, s)
+		case s := <-tr.Removed():
+			d.mu.Lock()
+			if dev := d.devices[s]; dev != nil {
+				dev.State = "offline"
+			}
+			d.mu.Unlock()
+		case <-tr.Errors():
+		case <-ticker.C:
+			for _, s := range d.online() {
+				go d.refresh(ctx, s)
+			}
+		}
+	}
+}
+
+func (d *Dashboard) online() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []string
+	for s, dev := range d.devices {
+		if dev.State == "online" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (d *Dashboard) load(ctx context.Context, serial string) {
+	info, err := d.opts.Client.Target(serial).DeviceInfo(ctx)
+	if err == nil {
+		d.update(serial, func(dev *Device) { dev.DeviceInfo = info })
+	}
+	d.refresh(ctx, serial)
+}
+
+func (d *Dashboard) refresh(ctx context.Context, serial string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	t := d.opts.Client.Target(serial)
+	if b, err := t.BatteryInfo(ctx); err == nil {
+		d.update(serial, func(dev *Device) { dev.Battery = &b })
+	}
+	if th, err := t.ThermalInfo(ctx); err == nil {
+		d.update(serial, func(dev *Device) { dev.Thermal = &th })
+	}
+}
+
+func (d *Dashboard) update(serial string, fn func(*Device)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dev := d.devices[serial]; dev != nil {
+		fn(dev)
+	}
+}
+
+// Devices returns the tracked devices, online first, then by serial.
+func (d *Dashboard) Devices() []Device {
+	d.mu.Lock()
+	out := make([]Device, 0, len(d.devices))
+	for _, dev := range d.devices {
+		out = append(out, *dev)
+	}
+	d.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].State != out[j].State {
+			return out[i].State == "online"
+		}
+		return out[i].Serial < out[j].Serial
+	})
+	return out
+}
+
+func (d *Dashboard) serveDevices(w http.ResponseWriter, r *http.Request) {
+	if !server.Authorized(r, d.opts.Token) {
+		writeJSON(w, http.StatusUnauthorized, api.ErrorBody{Error: "unauthorized"})
+		return
+	}
+	writeJSON(w, http.StatusOK, d.Devices())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,263 @@
+'use strict';
+
+// The page is opened as /?token=<token> when the dashboard has one. The token
+// moves to session storage and is sent as a header; only the capture <img>,
+// which cannot set headers, carries it in its URL.
+const token = (() => {
+  const q = new URLSearchParams(location.search);
+  const t = q.get('token');
+  if (t === null) return sessionStorage.getItem('hdckit-token') || '';
+  sessionStorage.setItem('hdckit-token', t);
+  q.delete('token');
+  history.replaceState(null, '', location.pathname + (q.toString() ? '?' + q : ''));
+  return t;
+})();
+
+const $ = (id) => document.getElementById(id);
+
+function api(path, opts = {}) {
+  if (token) opts.headers = { ...opts.headers, Authorization: 'Bearer ' + token };
+  return fetch(new URL(path, location.href), opts).then(async (r) => {
+    if (!r.ok) {
+      const body = await r.json().catch(() => ({}));
+      throw new Error(body.error || r.statusText);
+    }
+    return r;
+  });
+}
+
+function target(serial, op) {
+  return '/api/targets/' + encodeURIComponent(serial) + '/' + op;
+}
+
+let selected = null;
+let display = null;
+let layout = null;
+let hover = null;
+let logAbort = null;
+
+// device list
+
+async function loadDevices() {
+  let devices = [];
+  try {
+    devices = await (await api('/devices')).json();
+  } catch (e) {
+    console.warn('devices:', e);
+  }
+  const ul = $('device-list');
+  ul.replaceChildren(...devices.map((d) => {
+    const li = document.createElement('li');
+    li.className = d.state + (d.serial === selected ? ' selected' : '');
+    let vitals = '';
+    if (d.battery) vitals += ` · ${d.battery.level}%${d.battery.charge === 'charging' ? '+' : ''}`;
+    if (d.thermal && d.thermal.zones && d.thermal.zones.length) {
+      vitals += ` · ${Math.max(...d.thermal.zones.map((z) => z.temperature)).toFixed(0)}°C`;
+    }
+    li.innerHTML = '<span class="state"></span><div class="name"></div><div class="serial"></div><div class="muted os"></div>';
+    li.querySelector('.state').textContent = d.state + vitals;
+    li.querySelector('.name').textContent = [d.brand, d.model].filter(Boolean).join(' ') || 'unknown';
+    li.querySelector('.serial').textContent = d.serial;
+    li.querySelector('.os').textContent = d.osVersion ? `${d.osVersion} (API ${d.apiVersion})` : '';
+    if (d.state === 'online') li.onclick = () => select(d);
+    return li;
+  }));
+}
+
+async function select(d) {
+  if (d.serial === selected) return;
+  stopLog();
+  selected = d.serial;
+  display = null;
+  layout = null;
+  hover = null;
+  $('empty').hidden = true;
+  $('main').hidden = false;
+  $('title').textContent = `${d.model || d.serial} — ${d.serial}`;
+  $('node-info').innerHTML = '<p class="muted">Enable "Inspect layout" and hover the screen.</p>';
+  $('log').textContent = '';
+  drawOverlay();
+  loadDevices();
+  const img = $('capture');
+  const u = new URL(target(selected, 'capture'), location.href);
+  u.searchParams.set('fps', '10');
+  u.searchParams.set('scale', '0.5');
+  if (token) u.searchParams.set('token', token);
+  img.src = u;
+  try {
+    display = await (await api(target(selected, 'display'))).json();
+  } catch (e) {
+    console.warn('display:', e);
+  }
+  if ($('inspect').checked) loadLayout();
+}
+
+// screen: clicks become taps in device coordinates
+
+function devicePoint(ev) {
+  const img = $('capture');
+  const r = img.getBoundingClientRect();
+  const w = display ? display.width : img.naturalWidth;
+  const h = display ? display.height : img.naturalHeight;
+  return {
+    x: Math.round((ev.clientX - r.left) * w / r.width),
+    y: Math.round((ev.clientY - r.top) * h / r.height),
+  };
+}
+
+$('capture').addEventListener('click', async (ev) => {
+  if (!selected || $('inspect').checked) return;
+  const p = devicePoint(ev);
+  try {
+    await api(target(selected, 'ui/click'), {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(p),
+    });
+  } catch (e) {
+    console.warn('click:', e);
+  }
+});
+
+// layout inspector
+
+function parseBounds(s) {
+  const m = /\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]/.exec(s || '');
+  return m ? { x1: +m[1], y1: +m[2], x2: +m[3], y2: +m[4] } : null;
+}
+
+// flatten lists nodes in paint order with parsed bounds.
+function flatten(node, out = []) {
+  const b = parseBounds(node.attributes && node.attributes.bounds);
+  if (b) out.push({ attributes: node.attributes, bounds: b });
+  (node.children || []).forEach((c) => flatten(c, out));
+  return out;
+}
+
+async function loadLayout() {
+  if (!selected) return;
+  try {
+    layout = flatten(await (await api(target(selected, 'layout'))).json());
+  } catch (e) {
+    layout = null;
+    $('node-info').textContent = 'layout: ' + e.message;
+  }
+  drawOverlay();
+}
+
+// nodeAt returns the smallest node containing p; later nodes win ties.
+function nodeAt(p) {
+  let hit = null;
+  let area = Infinity;
+  for (const n of layout || []) {
+    const b = n.bounds;
+    if (p.x < b.x1 || p.x >= b.x2 || p.y < b.y1 || p.y >= b.y2) continue;
+    const a = (b.x2 - b.x1) * (b.y2 - b.y1);
+    if (a <= area) {
+      hit = n;
+      area = a;
+    }
+  }
+  return hit;
+}
+
+function drawOverlay() {
+  const img = $('capture');
+  const c = $('overlay');
+  c.width = img.clientWidth;
+  c.height = img.clientHeight;
+  const g = c.getContext('2d');
+  g.clearRect(0, 0, c.width, c.height);
+  if (!hover || !display) return;
+  const sx = c.width / display.width;
+  const sy = c.height / display.height;
+  const b = hover.bounds;
+  g.fillStyle = 'rgba(30, 120, 255, 0.2)';
+  g.strokeStyle = 'rgb(30, 120, 255)';
+  g.lineWidth = 2;
+  g.fillRect(b.x1 * sx, b.y1 * sy, (b.x2 - b.x1) * sx, (b.y2 - b.y1) * sy);
+  g.strokeRect(b.x1 * sx, b.y1 * sy, (b.x2 - b.x1) * sx, (b.y2 - b.y1) * sy);
+}
+
+function showNode(n) {
+  const info = $('node-info');
+  if (!n) {
+    info.innerHTML = '<p class="muted">No node under the cursor.</p>';
+    return;
+  }
+  const table = document.createElement('table');
+  Object.keys(n.attributes).sort().forEach((k) => {
+    const v = n.attributes[k];
+    if (v === '' || v === null) return;
+    const tr = table.insertRow();
+    tr.insertCell().textContent = k;
+    tr.insertCell().textContent = String(v);
+  });
+  info.replaceChildren(table);
+}
+
+$('capture').addEventListener('mousemove', (ev) => {
+  if (!$('inspect').checked || !layout) return;
+  const n = nodeAt(devicePoint(ev));
+  if (n === hover) return;
+  hover = n;
+  showNode(n);
+  drawOverlay();
+});
+
+$('inspect').addEventListener('change', (ev) => {
+  $('refresh-layout').disabled = !ev.target.checked;
+  hover = null;
+  drawOverlay();
+  if (ev.target.checked) loadLayout();
+});
+
+$('refresh-layout').addEventListener('click', loadLayout);
+window.addEventListener('resize', drawOverlay);
+
+// hilog tail
+
+const maxLogBytes = 512 * 1024;
+
+function stopLog() {
+  if (logAbort) logAbort.abort();
+  logAbort = null;
+  $('log-toggle').textContent = 'Start';
+}
+
+async function startLog() {
+  if (!selected) return;
+  logAbort = new AbortController();
+  $('log-toggle').textContent = 'Stop';
+  const log = $('log');
+  const decoder = new TextDecoder();
+  let partial = '';
+  try {
+    const r = await api(target(selected, 'hilog'), { signal: logAbort.signal });
+    const reader = r.body.getReader();
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      const lines = (partial + decoder.decode(value, { stream: true })).split('\n');
+      partial = lines.pop();
+      const filter = $('log-filter').value;
+      const keep = filter ? lines.filter((l) => l.includes(filter)) : lines;
+      if (!keep.length) continue;
+      const stick = log.scrollTop + log.clientHeight >= log.scrollHeight - 4;
+      log.textContent += keep.join('\n') + '\n';
+      if (log.textContent.length > maxLogBytes) {
+        log.textContent = log.textContent.slice(-maxLogBytes / 2);
+      }
+      if (stick) log.scrollTop = log.scrollHeight;
+    }
+  } catch (e) {
+    if (e.name !== 'AbortError') log.textContent += '[dashboard] hilog: ' + e.message + '\n';
+  }
+  stopLog();
+}
+
+$('log-toggle').addEventListener('click', () => (logAbort ? stopLog() : startLog()));
+$('log-clear').addEventListener('click', () => { $('log').textContent = ''; });
+
+loadDevices();
+setInterval(loadDevices, 5000);
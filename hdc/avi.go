@@ -0,0 +1,272 @@
+package hdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"time"
+)
+
+const (
+	aviAvihSize  = 56
+	aviStrhSize  = 56
+	aviStrfSize  = 40
+	aviFlagIndex = 0x10 // AVIF_HASINDEX
+	aviKeyFrame  = 0x10 // AVIIF_KEYFRAME
+)
+
+// AVIWriter writes JPEG frames into an MJPEG AVI container.
+//
+// AVI has a fixed frame rate, so frames are placed on a timeline of fps slots
+// per second using their timestamps. Gaps are filled with empty chunks, which
+// players treat as "repeat previous frame", keeping playback in real time
+// without duplicating image data.
+type AVIWriter struct {
+	w         io.WriteSeeker
+	fps       int
+	width     int
+	height    int
+	started   bool
+	closed    bool
+	moviStart int64 // offset of the 'movi' fourcc
+	pos       int64
+	slots     int
+	maxChunk  uint32
+	index     []aviIndexEntry
+}
+
+type aviIndexEntry struct {
+	flags  uint32
+	offset uint32
+	size   uint32
+}
+
+// NewAVIWriter creates a writer with the given timeline resolution (default 30 fps).
+func NewAVIWriter(w io.WriteSeeker, fps int) *AVIWriter {
+	if fps <= 0 {
+		fps = 30
+	}
+	return &AVIWriter{w: w, fps: fps}
+}
+
+// Frames returns the number of timeline slots written so far.
+func (a *AVIWriter) Frames() int { return a.slots }
+
+// WriteFrame appends a frame shown at ts (relative to the start of the recording).
+// PNG frames are transcoded to JPEG. Frames that land on an already written slot are dropped.
+func (a *AVIWriter) WriteFrame(frame []byte, ts time.Duration) error {
+	if a.closed {
+		return errors.New("avi writer closed")
+	}
+	data, err := toJPEG(frame)
+	if err != nil {
+		return err
+	}
+	if !a.started {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		a.width, a.height = cfg.Width, cfg.Height
+		if err := a.writeHeader(); err != nil {
+			return err
+		}
+		a.started = true
+	}
+	slot := int(ts * time.Duration(a.fps) / time.Second)
+	if a.slots > 0 && slot < a.slots {
+		return nil
+	}
+	for a.slots < slot {
+		if err := a.writeChunk(nil, 0); err != nil {
+			return err
+		}
+	}
+	return a.writeChunk(data, aviKeyFrame)
+}
+
+// Close writes the index and finalizes headers. It does not close the underlying writer.
+func (a *AVIWriter) Close() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	if !a.started {
+		return errors.New("no frames written")
+	}
+	var idx bytes.Buffer
+	for _, e := range a.index {
+		idx.WriteString("00dc")
+		le32(&idx, e.flags)
+		le32(&idx, e.offset)
+		le32(&idx, e.size)
+	}
+	moviSize := a.pos - a.moviStart
+	if err := a.writeRaw([]byte("idx1")); err != nil {
+		return err
+	}
+	if err := a.writeRaw(u32le(uint32(idx.Len()))); err != nil {
+		return err
+	}
+	if err := a.writeRaw(idx.Bytes()); err != nil {
+		return err
+	}
+	end := a.pos
+	// patch RIFF size, movi LIST size and frame counters
+	patches := []struct {
+		off int64
+		val uint32
+	}{
+		{4, uint32(end - 8)},
+		{a.moviStart - 4, uint32(moviSize)},
+		{aviAvihOffset + 16, uint32(a.slots)},
+		{aviAvihOffset + 28, a.maxChunk},
+		{aviStrhOffset + 32, uint32(a.slots)},
+		{aviStrhOffset + 36, a.maxChunk},
+	}
+	for _, p := range patches {
+		if _, err := a.w.Seek(p.off, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := a.w.Write(u32le(p.val)); err != nil {
+			return err
+		}
+	}
+	_, err := a.w.Seek(end, io.SeekStart)
+	return err
+}
+
+// byte offsets of the avih/strh payloads inside the fixed-size header
+const (
+	aviAvihOffset = 12 + 12 + 8
+	aviStrhOffset = aviAvihOffset + aviAvihSize + 12 + 8
+)
+
+func (a *AVIWriter) writeHeader() error {
+	var b bytes.Buffer
+	usPerFrame := uint32(1000000 / a.fps)
+	strl := 4 + 8 + aviStrhSize + 8 + aviStrfSize
+	hdrl := 4 + 8 + aviAvihSize + 8 + strl
+
+	b.WriteString("RIFF")
+	le32(&b, 0) // patched in Close
+	b.WriteString("AVI ")
+
+	b.WriteString("LIST")
+	le32(&b, uint32(hdrl))
+	b.WriteString("hdrl")
+	b.WriteString("avih")
+	le32(&b, aviAvihSize)
+	le32(&b, usPerFrame)
+	le32(&b, 0) // max bytes per sec
+	le32(&b, 0) // padding granularity
+	le32(&b, aviFlagIndex)
+	le32(&b, 0) // total frames, patched
+	le32(&b, 0) // initial frames
+	le32(&b, 1) // streams
+	le32(&b, 0) // suggested buffer size, patched
+	le32(&b, uint32(a.width))
+	le32(&b, uint32(a.height))
+	b.Write(make([]byte, 16))
+
+	b.WriteString("LIST")
+	le32(&b, uint32(strl))
+	b.WriteString("strl")
+	b.WriteString("strh")
+	le32(&b, aviStrhSize)
+	b.WriteString("vids")
+	b.WriteString("MJPG")
+	le32(&b, 0) // flags
+	le32(&b, 0) // priority + language
+	le32(&b, 0) // initial frames
+	le32(&b, 1) // scale
+	le32(&b, uint32(a.fps))
+	le32(&b, 0) // start
+	le32(&b, 0) // length, patched
+	le32(&b, 0) // suggested buffer size, patched
+	le32(&b, 0xFFFFFFFF)
+	le32(&b, 0) // sample size
+	le16(&b, 0)
+	le16(&b, 0)
+	le16(&b, uint16(a.width))
+	le16(&b, uint16(a.height))
+
+	b.WriteString("strf")
+	le32(&b, aviStrfSize)
+	le32(&b, aviStrfSize)
+	le32(&b, uint32(a.width))
+	le32(&b, uint32(a.height))
+	le16(&b, 1)  // planes
+	le16(&b, 24) // bit count
+	b.WriteString("MJPG")
+	le32(&b, uint32(a.width*a.height*3))
+	b.Write(make([]byte, 16))
+
+	b.WriteString("LIST")
+	le32(&b, 0) // movi size, patched
+	a.moviStart = int64(b.Len())
+	b.WriteString("movi")
+	return a.writeRaw(b.Bytes())
+}
+
+func (a *AVIWriter) writeChunk(data []byte, flags uint32) error {
+	off := uint32(a.pos - a.moviStart)
+	hdr := append([]byte("00dc"), u32le(uint32(len(data)))...)
+	if err := a.writeRaw(hdr); err != nil {
+		return err
+	}
+	if err := a.writeRaw(data); err != nil {
+		return err
+	}
+	if len(data)%2 == 1 {
+		if err := a.writeRaw([]byte{0}); err != nil {
+			return err
+		}
+	}
+	if uint32(len(data)) > a.maxChunk {
+		a.maxChunk = uint32(len(data))
+	}
+	a.index = append(a.index, aviIndexEntry{flags: flags, offset: off, size: uint32(len(data))})
+	a.slots++
+	return nil
+}
+
+func (a *AVIWriter) writeRaw(b []byte) error {
+	n, err := a.w.Write(b)
+	a.pos += int64(n)
+	return err
+}
+
+// toJPEG returns JPEG frames unchanged and re-encodes anything else image.Decode understands.
+func toJPEG(frame []byte) ([]byte, error) {
+	if imageFormat(frame) == "jpeg" {
+		return frame, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func u32le(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func le32(b *bytes.Buffer, v uint32) { b.Write(u32le(v)) }
+
+func le16(b *bytes.Buffer, v uint16) {
+	var x [2]byte
+	binary.LittleEndian.PutUint16(x[:], v)
+	b.Write(x[:])
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"io"
 	"net"
 	"strconv"
 	"time"
@@ -11,6 +12,9 @@ import (
 
 const handshakePrefix = "OHOS HDC"
 
+// errNoConn is returned by reads and writes on a closed or unconnected Connection.
+var errNoConn = errors.New("no conn")
+
 type Connection struct {
 	c          net.Conn
 	opts       Options
@@ -62,7 +66,7 @@ func (c *Connection) Close() {
 
 func (c *Connection) Send(payload []byte) error {
 	if c.c == nil {
-		return errors.New("no conn")
+		return errNoConn
 	}
 	var hdr [4]byte
 	binary.BigEndian.PutUint32(hdr[:], uint32(len(payload)))
@@ -72,7 +76,7 @@ func (c *Connection) Send(payload []byte) error {
 
 func (c *Connection) ReadBytes(ctx context.Context, n int) ([]byte, error) {
 	if c.c == nil {
-		return nil, errors.New("no conn")
+		return nil, errNoConn
 	}
 	buf := make([]byte, n)
 	_, err := ioReadFull(ctx, c.c, buf)
@@ -113,6 +117,18 @@ func (c *Connection) ReadAll(ctx context.Context) ([]byte, error) {
 	}
 }
 
+// readChunk reads the next value of a stream. The stream ending, because the
+// command exited or the connection was closed locally, is io.EOF; a broken
+// transport keeps its error.
+func (c *Connection) readChunk(ctx context.Context) ([]byte, error) {
+	b, err := c.ReadValue(ctx)
+	if err != nil && ctx.Err() == nil &&
+		(errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, errNoConn)) {
+		return nil, io.EOF
+	}
+	return b, err
+}
+
 func itoa(i int) string { return strconv.FormatInt(int64(i), 10) }
 
 // ioReadFull reads exactly len(buf) bytes, honoring context cancellation.
@@ -0,0 +1,56 @@
+package hdc
+
+import (
+	"context"
+	"time"
+)
+
+// Shell runs commands on a device.
+type Shell interface {
+	ShellOutput(ctx context.Context, command string) (string, error)
+}
+
+// FileTransfer copies files to and from a device.
+type FileTransfer interface {
+	SendFile(ctx context.Context, local, remote string) error
+	RecvFile(ctx context.Context, remote, local string) error
+}
+
+// Device is what tests need from a device besides its UI. *Target implements
+// it for local devices; package remote implements it over the hdckit HTTP server.
+type Device interface {
+	Shell
+	FileTransfer
+	// Key returns the device serial.
+	Key() string
+	GetParameters(ctx context.Context) (map[string]string, error)
+	Install(ctx context.Context, hap string) error
+	Uninstall(ctx context.Context, bundle string) error
+	Forward(ctx context.Context, local, remote string) error
+	RemoveForward(ctx context.Context, local, remote string) error
+	ListForwards(ctx context.Context) ([]Forward, error)
+}
+
+// UiController drives the UI of a device. *UiDriver implements it locally.
+type UiController interface {
+	Start(ctx context.Context) error
+	Stop()
+	Click(ctx context.Context, x, y int) error
+	TouchDown(ctx context.Context, x, y int) error
+	TouchMove(ctx context.Context, x, y int) error
+	TouchUp(ctx context.Context, x, y int) error
+	Swipe(ctx context.Context, x1, y1, x2, y2 int, dur time.Duration) error
+	PressBack(ctx context.Context) error
+	InputText(ctx context.Context, text string, x, y int) error
+	Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error)
+	CaptureLayout(ctx context.Context) (any, error)
+	DumpLayout(ctx context.Context) (*LayoutNode, error)
+	FindComponent(ctx context.Context, sel Selector) (*LayoutNode, error)
+	ClickComponent(ctx context.Context, sel Selector) error
+	GetDisplaySize(ctx context.Context) (DisplaySize, error)
+}
+
+var (
+	_ Device       = (*Target)(nil)
+	_ UiController = (*UiDriver)(nil)
+)
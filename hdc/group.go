@@ -0,0 +1,206 @@
+package hdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeviceGroup runs the same operation on several targets concurrently.
+type DeviceGroup struct {
+	Targets []*Target
+	// Parallel bounds how many targets run at once (default 4).
+	Parallel int
+	// NewDriver creates the UiDriver of ui operations such as Screenshot
+	// (default Target.CreateUiDriver); set it to pass UiDriverOptions or a display.
+	NewDriver func(t *Target) *UiDriver
+}
+
+// Group returns a group of the given targets.
+func (c *Client) Group(keys ...string) *DeviceGroup {
+	g := &DeviceGroup{}
+	for _, k := range keys {
+		g.Targets = append(g.Targets, c.Target(k))
+	}
+	return g
+}
+
+// GroupAll returns a group of every connected target.
+func (c *Client) GroupAll(ctx context.Context) (*DeviceGroup, error) {
+	keys, err := c.ListTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no devices connected")
+	}
+	return c.Group(keys...), nil
+}
+
+// DeviceResult is the outcome of a group operation on one target.
+type DeviceResult struct {
+	Target   string
+	Output   []byte
+	Err      error
+	Duration time.Duration
+}
+
+// GroupResult holds one DeviceResult per target, in group order.
+type GroupResult []DeviceResult
+
+// Err joins the per-device errors, each prefixed with its serial; nil when all succeeded.
+func (r GroupResult) Err() error {
+	var errs []error
+	for _, d := range r {
+		if d.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Target, d.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Failed returns the serials whose operation failed.
+func (r GroupResult) Failed() []string {
+	var out []string
+	for _, d := range r {
+		if d.Err != nil {
+			out = append(out, d.Target)
+		}
+	}
+	return out
+}
+
+// Print writes every output line prefixed with "[serial] ", followed by the
+// device's error, if any.
+func (r GroupResult) Print(w io.Writer) {
+	for _, d := range r {
+		prefix := "[" + d.Target + "] "
+		out := strings.TrimRight(string(d.Output), "\r\n")
+		if out != "" {
+			for _, l := range strings.Split(out, "\n") {
+				fmt.Fprintln(w, prefix+strings.TrimRight(l, "\r"))
+			}
+		}
+		if d.Err != nil {
+			fmt.Fprintf(w, "%serror: %v\n", prefix, d.Err)
+		}
+	}
+}
+
+// Run calls fn for every target, at most Parallel at a time, and collects the
+// results. fn receives ctx unchanged; cancel it to stop the remaining targets.
+func (g *DeviceGroup) Run(ctx context.Context, fn func(ctx context.Context, t *Target) ([]byte, error)) GroupResult {
+	n := g.Parallel
+	if n <= 0 {
+		n = 4
+	}
+	res := make(GroupResult, len(g.Targets))
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, t := range g.Targets {
+		res[i].Target = t.key
+		wg.Add(1)
+		go func(r *DeviceResult, t *Target) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			start := time.Now()
+			r.Output, r.Err = fn(ctx, t)
+			r.Duration = time.Since(start)
+		}(&res[i], t)
+	}
+	wg.Wait()
+	return res
+}
+
+// Shell runs command on every target.
+func (g *DeviceGroup) Shell(ctx context.Context, command string) GroupResult {
+	return g.Run(ctx, func(ctx context.Context, t *Target) ([]byte, error) {
+		c, err := t.Shell(ctx, command)
+		if err != nil {
+			return nil, err
+		}
+		return c.ReadAll(ctx)
+	})
+}
+
+// Install installs hap on every target.
+func (g *DeviceGroup) Install(ctx context.Context, hap string) GroupResult {
+	return g.Run(ctx, func(ctx context.Context, t *Target) ([]byte, error) {
+		return nil, t.Install(ctx, hap)
+	})
+}
+
+// SendFile pushes local to remote on every target.
+func (g *DeviceGroup) SendFile(ctx context.Context, local, remote string) GroupResult {
+	return g.Run(ctx, func(ctx context.Context, t *Target) ([]byte, error) {
+		return nil, t.SendFile(ctx, local, remote)
+	})
+}
+
+// Screenshot saves a screenshot of every target to out with "-<serial>"
+// inserted before the extension, e.g. shots/screen.png becomes
+// shots/screen-<serial>.png; without an extension, .png or .jpg follows
+// opts.Format. When out ends in a path separator, e.g. shots/, the files are
+// shots/<serial>.png. Missing directories are created. Each Output holds the
+// saved path.
+func (g *DeviceGroup) Screenshot(ctx context.Context, out string, opts ScreenshotOptions) GroupResult {
+	ext := filepath.Ext(out)
+	base := strings.TrimSuffix(out, ext) + "-"
+	if strings.HasSuffix(out, "/") || strings.HasSuffix(out, string(filepath.Separator)) {
+		ext, base = "", out
+	}
+	if ext == "" {
+		ext = screenshotExt(opts.Format)
+	}
+	return g.Run(ctx, func(ctx context.Context, t *Target) ([]byte, error) {
+		d := g.driver(t)
+		if err := d.Start(ctx); err != nil {
+			return nil, err
+		}
+		defer d.Stop()
+		b, err := d.Screenshot(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		path := base + SerialFileName(t.key) + ext
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			return nil, err
+		}
+		return []byte(path), nil
+	})
+}
+
+func (g *DeviceGroup) driver(t *Target) *UiDriver {
+	if g.NewDriver != nil {
+		return g.NewDriver(t)
+	}
+	return t.CreateUiDriver()
+}
+
+// SerialFileName makes a serial such as 192.168.1.2:5555 safe to use in file names.
+func SerialFileName(serial string) string {
+	return strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(serial)
+}
+
+func screenshotExt(format string) string {
+	switch strings.ToLower(format) {
+	case "jpg", "jpeg":
+		return ".jpg"
+	}
+	return ".png"
+}
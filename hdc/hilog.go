@@ -27,3 +27,12 @@ func (t *Target) OpenHilog(ctx context.Context, clear bool) (*HilogConnection, e
 	}
 	return &HilogConnection{conn: conn}, nil
 }
+
+// ReadChunk returns the next piece of log output, io.EOF once the stream ends.
+// Any other error means the hdc connection broke.
+func (h *HilogConnection) ReadChunk(ctx context.Context) ([]byte, error) {
+	return h.conn.readChunk(ctx)
+}
+
+// Close ends the hilog stream.
+func (h *HilogConnection) Close() { h.conn.Close() }
@@ -0,0 +1,296 @@
+package hdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrStaleObject is returned when a RemoteObject is used after Release or after
+// the connection it was created on has been replaced.
+var ErrStaleObject = errors.New("stale remote object")
+
+// remoteClasses are the agent classes whose instances come back as references
+// such as "Component#3". Other "Word#12" strings, e.g. a component text, stay strings.
+var remoteClasses = map[string]bool{
+	"Driver":          true,
+	"Component":       true,
+	"UiWindow":        true,
+	"On":              true,
+	"UIEventObserver": true,
+	"PointerMatrix":   true,
+	"UiDriver":        true,
+	"UiComponent":     true,
+	"By":              true,
+}
+
+// isRemoteRef reports whether s is a reference to an agent object.
+func isRemoteRef(s string) bool {
+	class, n, ok := strings.Cut(s, "#")
+	if !ok || !remoteClasses[class] || n == "" {
+		return false
+	}
+	for _, c := range n {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RemoteObject is a handle to an object living in the uitest agent, e.g. a
+// Component, UiWindow or On returned by a hypium api. The agent keeps the object
+// until it is released with Release or UiDriver.ReleaseObjects.
+type RemoteObject struct {
+	d        *UiDriver
+	conn     *uiRPCConn
+	ref      string
+	driver   bool
+	released atomic.Bool
+}
+
+// Ref returns the backend reference, e.g. "Component#3".
+func (o *RemoteObject) Ref() string {
+	if o.driver {
+		_, name := o.d.session()
+		return name
+	}
+	return o.ref
+}
+
+func (o *RemoteObject) String() string { return o.Ref() }
+
+// MarshalJSON encodes the object as its reference.
+func (o *RemoteObject) MarshalJSON() ([]byte, error) { return json.Marshal(o.Ref()) }
+
+// UnmarshalJSON reads a reference; the object is bound to a driver by Invoke.
+func (o *RemoteObject) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, &o.ref) }
+
+// Call invokes api with the object as "this".
+func (o *RemoteObject) Call(ctx context.Context, api string, args ...any) (any, error) {
+	return o.d.CallHypium(ctx, api, o, args...)
+}
+
+// Release frees the object in the agent. It is a no-op for released, stale and driver objects.
+func (o *RemoteObject) Release(ctx context.Context) error {
+	return o.d.ReleaseObjects(ctx, o)
+}
+
+// usable reports why the object cannot be sent, or nil.
+func (o *RemoteObject) usable() error {
+	if o.d == nil {
+		return fmt.Errorf("%w: %s is not bound to a driver", ErrStaleObject, o.ref)
+	}
+	if o.driver {
+		return nil
+	}
+	if o.released.Load() {
+		return fmt.Errorf("%w: %s was released", ErrStaleObject, o.ref)
+	}
+	if conn, _ := o.d.session(); conn != o.conn {
+		return fmt.Errorf("%w: %s belongs to a previous connection", ErrStaleObject, o.ref)
+	}
+	return nil
+}
+
+// Driver returns the handle of the driver object itself. It follows reconnects
+// and is never released.
+func (d *UiDriver) Driver() *RemoteObject {
+	return &RemoteObject{d: d, driver: true}
+}
+
+// CallHypium invokes any hypium api, e.g. "Driver.findComponent" or
+// "Component.getText". this is nil for static apis such as "On.text", a
+// *RemoteObject, or a raw reference string. *RemoteObject args are sent as
+// references; reference strings in the result come back as *RemoteObject,
+// also inside arrays.
+func (d *UiDriver) CallHypium(ctx context.Context, api string, this any, args ...any) (any, error) {
+	res, _, err := d.callHypiumVia(ctx, api, this, args...)
+	return res, err
+}
+
+// callHypiumVia is CallHypium, also returning the connection the result's
+// objects belong to.
+func (d *UiDriver) callHypiumVia(ctx context.Context, api string, this any, args ...any) (any, *uiRPCConn, error) {
+	if err := d.ensure(ctx); err != nil {
+		return nil, nil, err
+	}
+	var thisObj *RemoteObject
+	switch x := this.(type) {
+	case nil, string:
+	case *RemoteObject:
+		if err := x.usable(); err != nil {
+			return nil, nil, err
+		}
+		thisObj = x
+	default:
+		return nil, nil, fmt.Errorf("hypium %s: unsupported this type %T", api, this)
+	}
+	for _, a := range args {
+		if o, ok := a.(*RemoteObject); ok {
+			if err := o.usable(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if args == nil {
+		args = []any{}
+	}
+	// objects belong to the connection that answered, which is not the one
+	// current before the call when send reconnected and retried
+	res, conn, err := d.sendVia(ctx, func(name string) any {
+		var t any = this
+		switch {
+		case thisObj != nil && thisObj.driver:
+			t = name
+		case thisObj != nil:
+			t = thisObj.ref
+		}
+		return hypiumPayload(api, t, args)
+	})
+	if err != nil {
+		return nil, conn, fmt.Errorf("hypium %s: %w", api, err)
+	}
+	return d.wrapRemote(conn, res), conn, nil
+}
+
+// wrapRemote replaces reference strings in res (top level or array elements) with objects.
+func (d *UiDriver) wrapRemote(conn *uiRPCConn, res any) any {
+	switch x := res.(type) {
+	case string:
+		if isRemoteRef(x) {
+			return d.track(conn, x)
+		}
+	case []any:
+		for i, v := range x {
+			if s, ok := v.(string); ok && isRemoteRef(s) {
+				x[i] = d.track(conn, s)
+			}
+		}
+	}
+	return res
+}
+
+// track returns a new handle for ref, registered so ReleaseAll can free it.
+func (d *UiDriver) track(conn *uiRPCConn, ref string) *RemoteObject {
+	d.register(conn, ref)
+	return &RemoteObject{d: d, conn: conn, ref: ref}
+}
+
+func (d *UiDriver) register(conn *uiRPCConn, ref string) {
+	d.objMu.Lock()
+	if d.objects == nil {
+		d.objects = make(map[string]*uiRPCConn)
+	}
+	d.objects[ref] = conn
+	d.objMu.Unlock()
+}
+
+// ReleaseObjects frees objs in the agent with BackendObjectsCleaner.
+func (d *UiDriver) ReleaseObjects(ctx context.Context, objs ...*RemoteObject) error {
+	cur, _ := d.session()
+	var refs []any
+	d.objMu.Lock()
+	for _, o := range objs {
+		if o == nil || o.driver || o.released.Swap(true) {
+			continue
+		}
+		delete(d.objects, o.ref)
+		if o.conn == cur && cur != nil {
+			refs = append(refs, o.ref)
+		}
+	}
+	d.objMu.Unlock()
+	return d.releaseRefs(ctx, refs)
+}
+
+// ReleaseAll frees every object handed out on the current connection.
+// Handles from earlier connections are dropped without a call.
+func (d *UiDriver) ReleaseAll(ctx context.Context) error {
+	cur, _ := d.session()
+	var refs []any
+	d.objMu.Lock()
+	for ref, conn := range d.objects {
+		if conn == cur && cur != nil {
+			refs = append(refs, ref)
+		}
+	}
+	d.objects = nil
+	d.objMu.Unlock()
+	return d.releaseRefs(ctx, refs)
+}
+
+func (d *UiDriver) releaseRefs(ctx context.Context, refs []any) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	_, err := d.send(ctx, func(string) any { return hypiumPayload("BackendObjectsCleaner", nil, refs) })
+	return err
+}
+
+// Invoke calls a hypium api like UiDriver.CallHypium and decodes the result into T.
+// T may be *RemoteObject, []*RemoteObject or a struct holding them; decoded objects
+// are bound to d.
+func Invoke[T any](ctx context.Context, d *UiDriver, api string, this any, args ...any) (T, error) {
+	var out T
+	res, conn, err := d.callHypiumVia(ctx, api, this, args...)
+	if err != nil {
+		return out, err
+	}
+	if v, ok := res.(T); ok {
+		return v, nil
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, fmt.Errorf("hypium %s: decode %T: %w", api, out, err)
+	}
+	d.bindRemote(conn, reflect.ValueOf(&out))
+	return out, nil
+}
+
+var remoteObjectType = reflect.TypeOf((*RemoteObject)(nil))
+
+// bindRemote attaches every unbound *RemoteObject reachable from v to d and conn.
+func (d *UiDriver) bindRemote(conn *uiRPCConn, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if v.Type() == remoteObjectType {
+			o := v.Interface().(*RemoteObject)
+			if o.d == nil && o.ref != "" {
+				o.d, o.conn = d, conn
+				d.register(conn, o.ref)
+			}
+			return
+		}
+		d.bindRemote(conn, v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			d.bindRemote(conn, v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			d.bindRemote(conn, v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			d.bindRemote(conn, iter.Value())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				d.bindRemote(conn, v.Field(i))
+			}
+		}
+	}
+}
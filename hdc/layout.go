@@ -0,0 +1,237 @@
+package hdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrComponentNotFound is returned when no layout node matches a selector.
+var ErrComponentNotFound = errors.New("component not found")
+
+var reBounds = regexp.MustCompile(`\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]`)
+
+// LayoutNode is one node of the uitest layout tree returned by CaptureLayout.
+type LayoutNode struct {
+	Attributes map[string]string `json:"attributes"`
+	Children   []*LayoutNode     `json:"children"`
+	Parent     *LayoutNode       `json:"-"`
+}
+
+// ParseLayout converts a CaptureLayout result (decoded JSON, raw bytes or string) into a tree.
+func ParseLayout(v any) (*LayoutNode, error) {
+	var b []byte
+	switch x := v.(type) {
+	case []byte:
+		b = x
+	case string:
+		b = []byte(x)
+	default:
+		var err error
+		if b, err = json.Marshal(x); err != nil {
+			return nil, err
+		}
+	}
+	var raw rawLayoutNode
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse layout: %w", err)
+	}
+	return raw.convert(nil), nil
+}
+
+type rawLayoutNode struct {
+	Attributes map[string]any  `json:"attributes"`
+	Children   []rawLayoutNode `json:"children"`
+}
+
+// convert stringifies attribute values; uitest mixes strings, bools and numbers.
+func (r *rawLayoutNode) convert(parent *LayoutNode) *LayoutNode {
+	n := &LayoutNode{Attributes: make(map[string]string, len(r.Attributes)), Parent: parent}
+	for k, v := range r.Attributes {
+		switch x := v.(type) {
+		case string:
+			n.Attributes[k] = x
+		case nil:
+		default:
+			n.Attributes[k] = fmt.Sprint(x)
+		}
+	}
+	for i := range r.Children {
+		n.Children = append(n.Children, r.Children[i].convert(n))
+	}
+	return n
+}
+
+func (n *LayoutNode) Attr(key string) string { return n.Attributes[key] }
+func (n *LayoutNode) Text() string           { return n.Attributes["text"] }
+func (n *LayoutNode) ID() string             { return n.Attributes["id"] }
+func (n *LayoutNode) Key() string            { return n.Attributes["key"] }
+func (n *LayoutNode) Type() string           { return n.Attributes["type"] }
+func (n *LayoutNode) Description() string    { return n.Attributes["description"] }
+
+// Bool reads a "true"/"false" attribute such as clickable or enabled.
+func (n *LayoutNode) Bool(key string) bool { return n.Attributes[key] == "true" }
+
+// Bounds parses the "[x1,y1][x2,y2]" bounds attribute.
+func (n *LayoutNode) Bounds() image.Rectangle {
+	m := reBounds.FindStringSubmatch(n.Attributes["bounds"])
+	if len(m) != 5 {
+		return image.Rectangle{}
+	}
+	v := make([]int, 4)
+	for i := range v {
+		v[i], _ = strconv.Atoi(m[i+1])
+	}
+	return image.Rect(v[0], v[1], v[2], v[3])
+}
+
+// Center returns the middle of the node's bounds.
+func (n *LayoutNode) Center() image.Point {
+	b := n.Bounds()
+	return image.Pt((b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y)/2)
+}
+
+// Walk visits n and its descendants depth-first until fn returns false.
+func (n *LayoutNode) Walk(fn func(*LayoutNode) bool) bool {
+	if !fn(n) {
+		return false
+	}
+	for _, c := range n.Children {
+		if !c.Walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first node matching sel, or nil.
+func (n *LayoutNode) Find(sel Selector) *LayoutNode {
+	all := n.FindAll(sel)
+	if sel.Index < len(all) {
+		return all[sel.Index]
+	}
+	return nil
+}
+
+// FindAll returns all nodes matching sel in depth-first order. sel.Index is ignored.
+func (n *LayoutNode) FindAll(sel Selector) []*LayoutNode {
+	var out []*LayoutNode
+	n.Walk(func(c *LayoutNode) bool {
+		if sel.Match(c) {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// NodeAt returns the topmost visible node (last in paint order) whose bounds contain p.
+func (n *LayoutNode) NodeAt(p image.Point) *LayoutNode {
+	var hit *LayoutNode
+	n.Walk(func(c *LayoutNode) bool {
+		if c.Attributes["visible"] != "false" && p.In(c.Bounds()) {
+			hit = c
+		}
+		return true
+	})
+	return hit
+}
+
+// Selector matches layout nodes; empty fields are ignored.
+type Selector struct {
+	Text         string `json:"text,omitempty" yaml:"text,omitempty"`
+	TextContains string `json:"textContains,omitempty" yaml:"textContains,omitempty"`
+	ID           string `json:"id,omitempty" yaml:"id,omitempty"`
+	Key          string `json:"key,omitempty" yaml:"key,omitempty"`
+	Type         string `json:"type,omitempty" yaml:"type,omitempty"`
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Index picks the n-th match (0-based).
+	Index int `json:"index,omitempty" yaml:"index,omitempty"`
+}
+
+// Match reports whether n satisfies every non-empty field.
+func (s Selector) Match(n *LayoutNode) bool {
+	if s.Text != "" && n.Text() != s.Text {
+		return false
+	}
+	if s.TextContains != "" && !strings.Contains(n.Text(), s.TextContains) {
+		return false
+	}
+	if s.ID != "" && n.ID() != s.ID {
+		return false
+	}
+	if s.Key != "" && n.Key() != s.Key {
+		return false
+	}
+	if s.Type != "" && n.Type() != s.Type {
+		return false
+	}
+	if s.Description != "" && n.Description() != s.Description {
+		return false
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	var parts []string
+	add := func(k, v string) {
+		if v != "" {
+			parts = append(parts, fmt.Sprintf("%s=%q", k, v))
+		}
+	}
+	add("text", s.Text)
+	add("textContains", s.TextContains)
+	add("id", s.ID)
+	add("key", s.Key)
+	add("type", s.Type)
+	add("description", s.Description)
+	if s.Index > 0 {
+		parts = append(parts, "index="+strconv.Itoa(s.Index))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// DumpLayout captures the layout and parses it into a tree.
+func (d *UiDriver) DumpLayout(ctx context.Context) (*LayoutNode, error) {
+	v, err := d.CaptureLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLayout(v)
+}
+
+// FindComponent returns the first node matching sel in the current layout.
+// On a miss it runs the registered watchers and, if one fired, looks again.
+func (d *UiDriver) FindComponent(ctx context.Context, sel Selector) (*LayoutNode, error) {
+	root, err := d.DumpLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n := root.Find(sel); n != nil {
+		return n, nil
+	}
+	if fired, _ := d.RunWatchers(ctx); len(fired) > 0 {
+		if root, err = d.DumpLayout(ctx); err != nil {
+			return nil, err
+		}
+		if n := root.Find(sel); n != nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrComponentNotFound, sel)
+}
+
+// ClickComponent taps the center of the first node matching sel.
+func (d *UiDriver) ClickComponent(ctx context.Context, sel Selector) error {
+	n, err := d.FindComponent(ctx, sel)
+	if err != nil {
+		return err
+	}
+	c := n.Center()
+	return d.Click(ctx, c.X, c.Y)
+}
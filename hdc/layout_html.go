@@ -0,0 +1,109 @@
+package hdc
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//go:embed layout_viewer.html
+var layoutViewerHTML string
+
+var layoutViewer = template.Must(template.New("layout").Parse(layoutViewerHTML))
+
+// viewerNode is one node as the HTML viewer sees it.
+type viewerNode struct {
+	Bounds     [4]int            `json:"b"`
+	Depth      int               `json:"d"`
+	Attributes map[string]string `json:"a"`
+	Hidden     bool              `json:"h,omitempty"`
+}
+
+// WriteLayoutHTML writes a self-contained HTML page showing screenshot with
+// the bounds of every node of root drawn over it; hovering a box shows the
+// node's attributes, clicking pins them and a search box highlights nodes
+// whose attributes contain a string.
+func WriteLayoutHTML(w io.Writer, screenshot []byte, root *LayoutNode) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(screenshot))
+	if err != nil {
+		return err
+	}
+	width, height := cfg.Width, cfg.Height
+	var nodes []viewerNode
+	if root != nil {
+		if b := root.Bounds(); b.Dx() > 0 && b.Dy() > 0 {
+			width, height = b.Max.X, b.Max.Y
+		}
+		var walk func(n *LayoutNode, depth int)
+		walk = func(n *LayoutNode, depth int) {
+			b := n.Bounds()
+			nodes = append(nodes, viewerNode{
+				Bounds:     [4]int{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y},
+				Depth:      depth,
+				Attributes: n.Attributes,
+				Hidden:     n.Attributes["visible"] == "false",
+			})
+			for _, c := range n.Children {
+				walk(c, depth+1)
+			}
+		}
+		walk(root, 0)
+	}
+	return layoutViewer.Execute(w, map[string]any{
+		// data: URLs are rejected by html/template unless marked safe
+		"Image":  template.URL("data:image/" + format + ";base64," + base64.StdEncoding.EncodeToString(screenshot)),
+		"Width":  width,
+		"Height": height,
+		"Nodes":  nodes,
+	})
+}
+
+// DumpUI saves what ui shows now into dir, to find out why a selector does not
+// match: screen.png, layout.json (the raw CaptureLayout result), layout.html
+// (see WriteLayoutHTML) and bounds.png (see RenderLayout).
+func DumpUI(ctx context.Context, ui UiController, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	shot, err := ui.Screenshot(ctx, ScreenshotOptions{})
+	if err != nil {
+		return err
+	}
+	raw, err := ui.CaptureLayout(ctx)
+	if err != nil {
+		return err
+	}
+	root, err := ParseLayout(raw)
+	if err != nil {
+		return err
+	}
+	js, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	var page, bounds bytes.Buffer
+	if err := WriteLayoutHTML(&page, shot, root); err != nil {
+		return err
+	}
+	if err := WriteLayoutPNG(&bounds, shot, root); err != nil {
+		return err
+	}
+	for name, b := range map[string][]byte{
+		"screen.png":  shot,
+		"layout.json": js,
+		"layout.html": page.Bytes(),
+		"bounds.png":  bounds.Bytes(),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package hdc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+var (
+	boundsColor    = color.RGBA{0, 200, 80, 200}
+	highlightColor = color.RGBA{255, 40, 40, 255}
+)
+
+// RenderLayout draws the bounds of every visible node of root over screen.
+// Nodes in highlight are drawn thicker, in red. Layout coordinates are scaled
+// to the image when the screenshot was taken at another size than the layout.
+func RenderLayout(screen image.Image, root *LayoutNode, highlight ...*LayoutNode) *image.RGBA {
+	out := image.NewRGBA(screen.Bounds())
+	draw.Draw(out, out.Bounds(), screen, screen.Bounds().Min, draw.Src)
+	if root == nil {
+		return out
+	}
+	toImage := layoutScale(root, out.Bounds())
+	marked := make(map[*LayoutNode]bool, len(highlight))
+	for _, n := range highlight {
+		marked[n] = true
+	}
+	root.Walk(func(n *LayoutNode) bool {
+		if n.Attributes["visible"] != "false" && !marked[n] {
+			strokeRect(out, toImage(n.Bounds()), 1, boundsColor)
+		}
+		return true
+	})
+	// on top of everything else
+	for _, n := range highlight {
+		if n != nil {
+			strokeRect(out, toImage(n.Bounds()), 3, highlightColor)
+		}
+	}
+	return out
+}
+
+// WriteLayoutPNG decodes screenshot (PNG or JPEG), renders root over it and
+// writes the result to w as PNG, e.g. for a CI artifact.
+func WriteLayoutPNG(w io.Writer, screenshot []byte, root *LayoutNode, highlight ...*LayoutNode) error {
+	img, _, err := image.Decode(bytes.NewReader(screenshot))
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, RenderLayout(img, root, highlight...))
+}
+
+// layoutScale maps layout rectangles into img, using the root bounds as the
+// size of the screen.
+func layoutScale(root *LayoutNode, img image.Rectangle) func(image.Rectangle) image.Rectangle {
+	screen := root.Bounds()
+	if screen.Dx() <= 0 || screen.Dy() <= 0 || (screen.Dx() == img.Dx() && screen.Dy() == img.Dy()) {
+		return func(r image.Rectangle) image.Rectangle { return r.Add(img.Min) }
+	}
+	sx := float64(img.Dx()) / float64(screen.Max.X)
+	sy := float64(img.Dy()) / float64(screen.Max.Y)
+	return func(r image.Rectangle) image.Rectangle {
+		return image.Rect(
+			int(float64(r.Min.X)*sx), int(float64(r.Min.Y)*sy),
+			int(float64(r.Max.X)*sx), int(float64(r.Max.Y)*sy),
+		).Add(img.Min)
+	}
+}
+
+// strokeRect draws the outline of r, width pixels wide, inside r.
+func strokeRect(img draw.Image, r image.Rectangle, width int, c color.Color) {
+	r = r.Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	src := image.NewUniform(c)
+	w := min(width, r.Dx(), r.Dy())
+	for _, edge := range []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+w),
+		image.Rect(r.Min.X, r.Max.Y-w, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y+w, r.Min.X+w, r.Max.Y-w),
+		image.Rect(r.Max.X-w, r.Min.Y+w, r.Max.X, r.Max.Y-w),
+	} {
+		draw.Draw(img, edge, src, image.Point{}, draw.Over)
+	}
+}
@@ -0,0 +1,166 @@
+package hdc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const mjpegBoundary = "hdckitframe"
+
+// MJPEGStreamer serves capture frames to HTTP clients as a
+// multipart/x-mixed-replace (MJPEG) stream.
+//
+// Publish never blocks, so it can be passed directly as the StartCaptureScreen
+// callback. Each client only holds the newest frame; slow clients drop frames
+// instead of stalling the capture.
+type MJPEGStreamer struct {
+	fps     int
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	last    []byte
+	done    chan struct{}
+	closed  bool
+}
+
+// NewMJPEGStreamer creates a streamer limited to fps frames per second per client.
+// fps <= 0 disables the limit.
+func NewMJPEGStreamer(fps int) *MJPEGStreamer {
+	return &MJPEGStreamer{
+		fps:     fps,
+		clients: make(map[chan []byte]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Publish hands a frame to all connected clients, replacing any frame they have not sent yet.
+// The streamer keeps frame without copying it, so the caller must not modify it
+// afterwards; frames from StartCaptureScreen are never modified.
+func (s *MJPEGStreamer) Publish(frame []byte) {
+	if len(frame) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = frame
+	for ch := range s.clients {
+		offerLatest(ch, frame)
+	}
+}
+
+// Clients returns the number of connected viewers.
+func (s *MJPEGStreamer) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Close ends all client streams. Further requests get 503.
+func (s *MJPEGStreamer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *MJPEGStreamer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan []byte, 1)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		http.Error(w, "stream closed", http.StatusServiceUnavailable)
+		return
+	}
+	s.clients[ch] = struct{}{}
+	if s.last != nil {
+		ch <- s.last
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	h := w.Header()
+	h.Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	h.Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	h.Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var interval time.Duration
+	if s.fps > 0 {
+		interval = time.Second / time.Duration(s.fps)
+	}
+	var lastSent time.Time
+	for {
+		var b []byte
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case b = <-ch:
+		}
+		if wait := interval - time.Since(lastSent); interval > 0 && wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			case <-s.done:
+				t.Stop()
+				return
+			case <-t.C:
+			}
+			// a newer frame may have arrived while throttled
+			select {
+			case nb := <-ch:
+				b = nb
+			default:
+			}
+		}
+		if err := writeMJPEGPart(w, b); err != nil {
+			return
+		}
+		flusher.Flush()
+		lastSent = time.Now()
+	}
+}
+
+func writeMJPEGPart(w http.ResponseWriter, b []byte) error {
+	if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, imageContentType(b), len(b)); err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte("\r\n"))
+	return err
+}
+
+// offerLatest puts b into a 1-slot channel, discarding the pending value if full.
+func offerLatest(ch chan []byte, b []byte) {
+	select {
+	case ch <- b:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- b:
+	default:
+	}
+}
@@ -0,0 +1,511 @@
+package hdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a declarative test written in YAML:
+//
+//	name: login
+//	steps:
+//	  - launch: com.example.app/EntryAbility
+//	  - click: {text: Login}
+//	  - input: {text: alice, target: {id: user}}
+//	  - swipe: up
+//	  - wait: {text: Home}
+//	    timeout: 10s
+//	  - assert_exists: {textContains: Welcome}
+//	  - screenshot: home.png
+//	  - shell: {cmd: "ls /data/local/tmp", expect: agent.so}
+//	  - pull: {remote: /data/local/tmp/log.txt, local: log.txt}
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Target is the device key, used when the command line names none.
+	Target string         `yaml:"target,omitempty"`
+	Steps  []ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioStep is one step: a single action key plus the optional name and timeout.
+type ScenarioStep struct {
+	Name    string
+	Action  string
+	Timeout time.Duration
+
+	// Selector is the component of click, input, wait and assert_exists.
+	Selector *Selector
+	// Point is the tap point of click: [x, y], or the start of a swipe.
+	Point    *image.Point
+	To       image.Point
+	Dir      string
+	Duration time.Duration
+	Text     string
+	Clear    bool
+	Bundle   string
+	Ability  string
+	Command  string
+	Expect   string
+	Remote   string
+	Local    string
+	File     string
+}
+
+// ScenarioActions lists the step actions a Scenario understands.
+var ScenarioActions = []string{"launch", "click", "input", "swipe", "wait", "assert_exists", "screenshot", "shell", "pull"}
+
+// LoadScenario reads a YAML scenario; the name defaults to the file name.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	if err := yaml.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("load scenario %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &sc, nil
+}
+
+// UnmarshalYAML decodes `- action: value` plus the name and timeout keys.
+func (s *ScenarioStep) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		// bare actions without arguments, e.g. `- screenshot`
+		s.Action = n.Value
+		return s.decode(nil)
+	}
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: step must be a mapping", n.Line)
+	}
+	var arg *yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		switch k.Value {
+		case "name":
+			s.Name = v.Value
+		case "timeout":
+			if err := v.Decode(&s.Timeout); err != nil {
+				return fmt.Errorf("line %d: timeout: %w", v.Line, err)
+			}
+		default:
+			if s.Action != "" {
+				return fmt.Errorf("line %d: step has two actions, %s and %s", k.Line, s.Action, k.Value)
+			}
+			s.Action, arg = k.Value, v
+		}
+	}
+	if s.Action == "" {
+		return fmt.Errorf("line %d: step has no action", n.Line)
+	}
+	if err := s.decode(arg); err != nil {
+		return fmt.Errorf("line %d: %s: %w", n.Line, s.Action, err)
+	}
+	return nil
+}
+
+func (s *ScenarioStep) decode(v *yaml.Node) error {
+	scalar := v != nil && v.Kind == yaml.ScalarNode
+	mapping := v != nil && v.Kind == yaml.MappingNode
+	switch s.Action {
+	case "launch":
+		if scalar {
+			s.Bundle, s.Ability, _ = strings.Cut(v.Value, "/")
+		} else if mapping {
+			var a struct{ Bundle, Ability string }
+			if err := v.Decode(&a); err != nil {
+				return err
+			}
+			s.Bundle, s.Ability = a.Bundle, a.Ability
+		}
+		if s.Bundle == "" {
+			return errors.New("bundle required")
+		}
+		if s.Ability == "" {
+			s.Ability = "EntryAbility"
+		}
+	case "click", "wait", "assert_exists":
+		switch {
+		case s.Action == "click" && v != nil && v.Kind == yaml.SequenceNode:
+			var xy []int
+			if err := v.Decode(&xy); err != nil || len(xy) != 2 {
+				return errors.New("point must be [x, y]")
+			}
+			s.Point = &image.Point{X: xy[0], Y: xy[1]}
+		case s.Action == "wait" && scalar && isDuration(v.Value):
+			s.Duration, _ = time.ParseDuration(v.Value)
+		case scalar:
+			s.Selector = &Selector{Text: v.Value}
+		case mapping:
+			var sel Selector
+			if err := v.Decode(&sel); err != nil {
+				return err
+			}
+			var t struct {
+				Timeout time.Duration `yaml:"timeout"`
+			}
+			if err := v.Decode(&t); err != nil {
+				return err
+			}
+			s.Selector = &sel
+			if t.Timeout > 0 {
+				s.Timeout = t.Timeout
+			}
+		default:
+			return errors.New("selector required")
+		}
+	case "input":
+		if scalar {
+			s.Text = v.Value
+			return nil
+		}
+		var a struct {
+			Text   string
+			Target *Selector
+			Clear  bool
+		}
+		if v == nil {
+			return errors.New("text required")
+		}
+		if err := v.Decode(&a); err != nil {
+			return err
+		}
+		s.Text, s.Selector, s.Clear = a.Text, a.Target, a.Clear
+	case "swipe":
+		if scalar {
+			switch v.Value {
+			case "up", "down", "left", "right":
+				s.Dir = v.Value
+				return nil
+			}
+			return fmt.Errorf("unknown direction %q", v.Value)
+		}
+		var a struct {
+			From, To []int
+			Duration time.Duration
+		}
+		if v == nil {
+			return errors.New("direction or from/to required")
+		}
+		if err := v.Decode(&a); err != nil {
+			return err
+		}
+		if len(a.From) != 2 || len(a.To) != 2 {
+			return errors.New("from and to must be [x, y]")
+		}
+		s.Point = &image.Point{X: a.From[0], Y: a.From[1]}
+		s.To = image.Pt(a.To[0], a.To[1])
+		s.Duration = a.Duration
+	case "screenshot":
+		if scalar {
+			s.File = v.Value
+		}
+	case "shell":
+		if scalar {
+			s.Command = v.Value
+		} else if mapping {
+			var a struct{ Cmd, Expect string }
+			if err := v.Decode(&a); err != nil {
+				return err
+			}
+			s.Command, s.Expect = a.Cmd, a.Expect
+		}
+		if s.Command == "" {
+			return errors.New("command required")
+		}
+	case "pull":
+		if scalar {
+			s.Remote = v.Value
+		} else if mapping {
+			var a struct{ Remote, Local string }
+			if err := v.Decode(&a); err != nil {
+				return err
+			}
+			s.Remote, s.Local = a.Remote, a.Local
+		}
+		if s.Remote == "" {
+			return errors.New("remote path required")
+		}
+	default:
+		return fmt.Errorf("unknown action (want one of %s)", strings.Join(ScenarioActions, ", "))
+	}
+	return nil
+}
+
+func (s ScenarioStep) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch s.Action {
+	case "launch":
+		return "launch " + s.Bundle + "/" + s.Ability
+	case "click", "wait", "assert_exists":
+		switch {
+		case s.Selector != nil:
+			return s.Action + " " + s.Selector.String()
+		case s.Point != nil:
+			return fmt.Sprintf("click (%d,%d)", s.Point.X, s.Point.Y)
+		}
+		return fmt.Sprintf("wait %s", s.Duration)
+	case "input":
+		return fmt.Sprintf("input %q", s.Text)
+	case "swipe":
+		if s.Dir != "" {
+			return "swipe " + s.Dir
+		}
+		return fmt.Sprintf("swipe %v -> %v", *s.Point, s.To)
+	case "shell":
+		return "shell " + s.Command
+	case "pull":
+		return "pull " + s.Remote
+	}
+	return strings.TrimSpace(s.Action + " " + s.File)
+}
+
+// ScenarioOptions tunes RunScenario.
+type ScenarioOptions struct {
+	// OutDir receives the per-step screenshots and pulled files (default ".").
+	OutDir string
+	// Timeout is the default wait for a step's component (default 10s).
+	Timeout time.Duration
+	// NoStepScreenshots skips the screenshot taken after every step.
+	NoStepScreenshots bool
+	// OnStep is called after each step.
+	OnStep func(r StepResult)
+}
+
+// StepResult is the outcome of one scenario step.
+type StepResult struct {
+	Index    int
+	Name     string
+	Action   string
+	Duration time.Duration
+	Err      error
+	// Skipped is set for the steps after a failure.
+	Skipped bool
+	// Screenshot is the file saved after the step, if any.
+	Screenshot string
+	// Output is the shell output of a shell step.
+	Output string
+}
+
+// ScenarioResult is the outcome of RunScenario.
+type ScenarioResult struct {
+	Name     string
+	Target   string
+	Start    time.Time
+	Duration time.Duration
+	Steps    []StepResult
+}
+
+// Failed reports whether a step failed.
+func (r *ScenarioResult) Failed() bool {
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RunScenario runs the steps in order through d and its Target. The first
+// failing step ends the run; the remaining steps are reported as skipped.
+func (d *UiDriver) RunScenario(ctx context.Context, sc *Scenario, opts ScenarioOptions) *ScenarioResult {
+	if opts.OutDir == "" {
+		opts.OutDir = "."
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	res := &ScenarioResult{Name: sc.Name, Target: d.target.key, Start: time.Now()}
+	failed := false
+	for i, st := range sc.Steps {
+		r := StepResult{Index: i, Name: st.String(), Action: st.Action}
+		if failed || ctx.Err() != nil {
+			r.Skipped = true
+			res.Steps = append(res.Steps, r)
+			continue
+		}
+		start := time.Now()
+		r.Output, r.Screenshot, r.Err = d.runScenarioStep(ctx, i, st, opts)
+		r.Duration = time.Since(start)
+		if r.Screenshot == "" && !opts.NoStepScreenshots {
+			r.Screenshot = d.stepScreenshot(ctx, filepath.Join(opts.OutDir, fmt.Sprintf("%02d-%s.png", i+1, st.Action)))
+		}
+		failed = r.Err != nil
+		res.Steps = append(res.Steps, r)
+		if opts.OnStep != nil {
+			opts.OnStep(r)
+		}
+	}
+	res.Duration = time.Since(res.Start)
+	return res
+}
+
+// runScenarioStep returns the shell output and the screenshot written by the step.
+func (d *UiDriver) runScenarioStep(ctx context.Context, i int, st ScenarioStep, opts ScenarioOptions) (string, string, error) {
+	timeout := st.Timeout
+	if timeout <= 0 {
+		timeout = opts.Timeout
+	}
+	switch st.Action {
+	case "launch":
+		return "", "", d.target.StartAbility(ctx, st.Bundle, st.Ability)
+	case "click":
+		p := st.Point
+		if st.Selector != nil {
+			n, err := d.waitComponent(ctx, *st.Selector, timeout)
+			if err != nil {
+				return "", "", err
+			}
+			c := n.Center()
+			p = &c
+		}
+		return "", "", d.Click(ctx, p.X, p.Y)
+	case "wait":
+		if st.Selector == nil {
+			return "", "", sleepCtx(ctx, st.Duration)
+		}
+		_, err := d.waitComponent(ctx, *st.Selector, timeout)
+		return "", "", err
+	case "assert_exists":
+		_, err := d.waitComponent(ctx, *st.Selector, timeout)
+		if err != nil {
+			return "", "", fmt.Errorf("assertion failed: %w", err)
+		}
+		return "", "", nil
+	case "input":
+		opts := InputTextOptions{Clear: st.Clear}
+		if st.Selector != nil {
+			n, err := d.waitComponent(ctx, *st.Selector, timeout)
+			if err != nil {
+				return "", "", err
+			}
+			c := n.Center()
+			opts.At = &c
+		}
+		return "", "", d.InputTextWith(ctx, st.Text, opts)
+	case "swipe":
+		from, to := st.Point, st.To
+		if st.Dir != "" {
+			size, err := d.GetDisplaySize(ctx)
+			if err != nil {
+				return "", "", err
+			}
+			f, t := swipeVector(st.Dir, size)
+			from, to = &f, t
+		}
+		dur := st.Duration
+		if dur <= 0 {
+			dur = 300 * time.Millisecond
+		}
+		return "", "", d.swipe(ctx, *from, to, dur)
+	case "screenshot":
+		name := st.File
+		if name == "" {
+			name = fmt.Sprintf("%02d-screenshot.png", i+1)
+		}
+		path := filepath.Join(opts.OutDir, name)
+		if err := d.saveScreenshot(ctx, path); err != nil {
+			return "", "", err
+		}
+		return "", path, nil
+	case "shell":
+		out, err := d.target.shellOutput(ctx, st.Command)
+		if err != nil {
+			return out, "", err
+		}
+		if st.Expect != "" && !strings.Contains(out, st.Expect) {
+			return out, "", fmt.Errorf("output does not contain %q", st.Expect)
+		}
+		return out, "", nil
+	case "pull":
+		local := st.Local
+		if local == "" {
+			local = filepath.Base(st.Remote)
+		}
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(opts.OutDir, local)
+		}
+		if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return "", "", err
+		}
+		return "", "", d.target.RecvFile(ctx, st.Remote, local)
+	}
+	return "", "", fmt.Errorf("unknown action %q", st.Action)
+}
+
+// waitComponent waits up to timeout for a node matching sel.
+func (d *UiDriver) waitComponent(ctx context.Context, sel Selector, timeout time.Duration) (*LayoutNode, error) {
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var found *LayoutNode
+	cond := NewCondition("component "+sel.String()+" exists", func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		n, err := d.FindComponent(ctx, sel)
+		if err != nil {
+			return false, nil, err
+		}
+		found = n
+		return true, n.Attr("bounds"), nil
+	})
+	if err := d.WaitUntil(wctx, cond, 300*time.Millisecond); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func isDuration(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+// swipeVector spans 40% of the screen through its center in direction dir.
+func swipeVector(dir string, size DisplaySize) (image.Point, image.Point) {
+	cx, cy := size.Width/2, size.Height/2
+	dx, dy := size.Width/5, size.Height/5
+	switch dir {
+	case "up":
+		return image.Pt(cx, cy+dy), image.Pt(cx, cy-dy)
+	case "down":
+		return image.Pt(cx, cy-dy), image.Pt(cx, cy+dy)
+	case "left":
+		return image.Pt(cx+dx, cy), image.Pt(cx-dx, cy)
+	}
+	return image.Pt(cx-dx, cy), image.Pt(cx+dx, cy)
+}
+
+func (d *UiDriver) saveScreenshot(ctx context.Context, path string) error {
+	b, err := d.Screenshot(ctx, ScreenshotOptions{})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// stepScreenshot saves a screenshot for the report; failures only cost the picture.
+func (d *UiDriver) stepScreenshot(ctx context.Context, path string) string {
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := d.saveScreenshot(sctx, path); err != nil {
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] step screenshot %s: %v\n", path, err)
+		}
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,113 @@
+package hdc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Hostname  string      `xml:"hostname,attr,omitempty"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as a JUnit XML report: one testsuite per scenario
+// and one testcase per step, with the step screenshot in system-out.
+func WriteJUnit(w io.Writer, results ...*ScenarioResult) error {
+	var all junitSuites
+	var total float64
+	for _, r := range results {
+		s := junitSuite{
+			Name:      r.Name,
+			Hostname:  r.Target,
+			Time:      seconds(r.Duration.Seconds()),
+			Timestamp: r.Start.UTC().Format("2006-01-02T15:04:05"),
+		}
+		for _, st := range r.Steps {
+			c := junitCase{
+				Name:      fmt.Sprintf("%02d %s", st.Index+1, st.Name),
+				Classname: r.Name,
+				Time:      seconds(st.Duration.Seconds()),
+			}
+			switch {
+			case st.Skipped:
+				c.Skipped = &struct{}{}
+				s.Skipped++
+			case st.Err != nil:
+				c.Failure = &junitFailure{Message: st.Err.Error(), Type: st.Action, Text: st.Output}
+				s.Failures++
+			}
+			if st.Screenshot != "" {
+				c.SystemOut = "[[ATTACHMENT|" + st.Screenshot + "]]"
+			}
+			s.Cases = append(s.Cases, c)
+		}
+		s.Tests = len(s.Cases)
+		all.Tests += s.Tests
+		all.Failures += s.Failures
+		all.Skipped += s.Skipped
+		total += r.Duration.Seconds()
+		all.Suites = append(all.Suites, s)
+	}
+	all.Time = seconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(all); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJUnitFile writes the JUnit report to path, creating its directory.
+func WriteJUnitFile(path string, results ...*ScenarioResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteJUnit(f, results...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func seconds(s float64) string { return fmt.Sprintf("%.3f", s) }
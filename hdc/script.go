@@ -0,0 +1,238 @@
+package hdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StepAction is the gesture of a script Step.
+type StepAction string
+
+const (
+	StepClick       StepAction = "click"
+	StepDoubleClick StepAction = "doubleClick"
+	StepLongClick   StepAction = "longClick"
+	StepSwipe       StepAction = "swipe"
+	StepInput       StepAction = "input"
+	StepBack        StepAction = "back"
+	StepKey         StepAction = "key"
+	StepWait        StepAction = "wait"
+)
+
+// Step is one recorded gesture. On replay Target wins over the raw coordinates,
+// which are the fallback when the component is not found.
+type Step struct {
+	Action StepAction `json:"action" yaml:"action"`
+	// Target is the component the gesture started on, resolved at record time.
+	Target *Selector `json:"target,omitempty" yaml:"target,omitempty"`
+	X      int       `json:"x,omitempty" yaml:"x,omitempty"`
+	Y      int       `json:"y,omitempty" yaml:"y,omitempty"`
+	// ToX, ToY end a swipe.
+	ToX  int    `json:"toX,omitempty" yaml:"toX,omitempty"`
+	ToY  int    `json:"toY,omitempty" yaml:"toY,omitempty"`
+	Text string `json:"text,omitempty" yaml:"text,omitempty"`
+	// Key is the key code of a key step.
+	Key int `json:"key,omitempty" yaml:"key,omitempty"`
+	// DurationMs is how long the finger was down, or the pause of a wait step.
+	DurationMs int `json:"durationMs,omitempty" yaml:"durationMs,omitempty"`
+	// DelayMs is the pause before the step, as recorded.
+	DelayMs int `json:"delayMs,omitempty" yaml:"delayMs,omitempty"`
+	// Desc is a human readable summary, e.g. `click component with text "OK"`.
+	Desc string `json:"desc,omitempty" yaml:"desc,omitempty"`
+}
+
+func (s Step) String() string {
+	if s.Desc != "" {
+		return s.Desc
+	}
+	if s.Target != nil {
+		return fmt.Sprintf("%s %s", s.Action, s.Target)
+	}
+	return fmt.Sprintf("%s (%d,%d)", s.Action, s.X, s.Y)
+}
+
+// Script is a replayable list of steps, stored as YAML or JSON.
+type Script struct {
+	Version int    `json:"version" yaml:"version"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Steps   []Step `json:"steps" yaml:"steps"`
+}
+
+// LoadScript reads a script; .json files are parsed as JSON, anything else as YAML.
+func LoadScript(path string) (*Script, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Script
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &s)
+	} else {
+		err = yaml.Unmarshal(b, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load script %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the script as JSON for .json paths and YAML otherwise.
+func (s *Script) Save(path string) error {
+	if s.Version == 0 {
+		s.Version = 1
+	}
+	var b []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		b, err = json.MarshalIndent(s, "", "  ")
+	} else {
+		b, err = yaml.Marshal(s)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// PlayOptions tunes PlayScript.
+type PlayOptions struct {
+	// Speed scales the recorded delays: 2 plays twice as fast (default 1).
+	Speed float64
+	// NoDelay skips the recorded delays.
+	NoDelay bool
+	// FindTimeout bounds the wait for a step's target component (default 5s).
+	FindTimeout time.Duration
+	// OnStep is called before each step.
+	OnStep func(i int, s Step)
+}
+
+// StepError reports the step a script failed on.
+type StepError struct {
+	Index int
+	Step  Step
+	Err   error
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("step %d (%s): %v", e.Index+1, e.Step, e.Err) }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// PlayScript replays s through the driver.
+func (d *UiDriver) PlayScript(ctx context.Context, s *Script, opts PlayOptions) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	if opts.FindTimeout <= 0 {
+		opts.FindTimeout = 5 * time.Second
+	}
+	for i, st := range s.Steps {
+		if st.DelayMs > 0 && !opts.NoDelay {
+			delay := time.Duration(float64(st.DelayMs)/opts.Speed) * time.Millisecond
+			if err := sleepCtx(ctx, delay); err != nil {
+				return err
+			}
+		}
+		if opts.OnStep != nil {
+			opts.OnStep(i, st)
+		}
+		if err := d.playStep(ctx, st, opts); err != nil {
+			return &StepError{Index: i, Step: st, Err: err}
+		}
+	}
+	return nil
+}
+
+func (d *UiDriver) playStep(ctx context.Context, st Step, opts PlayOptions) error {
+	dur := time.Duration(st.DurationMs) * time.Millisecond
+	switch st.Action {
+	case StepWait:
+		return sleepCtx(ctx, dur)
+	case StepBack:
+		return d.PressBack(ctx)
+	case StepKey:
+		_, err := d.callHypium(ctx, "Driver.triggerKey", st.Key)
+		return err
+	}
+	p, err := d.stepPoint(ctx, st, opts.FindTimeout)
+	if err != nil {
+		return err
+	}
+	switch st.Action {
+	case StepClick:
+		return d.Click(ctx, p.X, p.Y)
+	case StepDoubleClick:
+		if err := d.Click(ctx, p.X, p.Y); err != nil {
+			return err
+		}
+		return d.Click(ctx, p.X, p.Y)
+	case StepLongClick:
+		if dur < 500*time.Millisecond {
+			dur = 1500 * time.Millisecond
+		}
+		if err := d.TouchDown(ctx, p.X, p.Y); err != nil {
+			return err
+		}
+		if err := sleepCtx(ctx, dur); err != nil {
+			_ = d.TouchUp(context.Background(), p.X, p.Y)
+			return err
+		}
+		return d.TouchUp(ctx, p.X, p.Y)
+	case StepSwipe:
+		if dur <= 0 {
+			dur = 300 * time.Millisecond
+		}
+		// keep the recorded vector so a moved target swipes the same way
+		to := p.Add(image.Pt(st.ToX-st.X, st.ToY-st.Y))
+		return d.swipe(ctx, p, to, dur)
+	case StepInput:
+		opts := InputTextOptions{}
+		if st.Target != nil || st.X != 0 || st.Y != 0 {
+			opts.At = &p
+		}
+		return d.InputTextWith(ctx, st.Text, opts)
+	}
+	return fmt.Errorf("unknown action %q", st.Action)
+}
+
+// stepPoint waits for the step's target and returns its center, or the raw point.
+func (d *UiDriver) stepPoint(ctx context.Context, st Step, timeout time.Duration) (image.Point, error) {
+	raw := image.Pt(st.X, st.Y)
+	if st.Target == nil {
+		return raw, nil
+	}
+	n, err := d.waitComponent(ctx, *st.Target, timeout)
+	if err == nil {
+		return n.Center(), nil
+	}
+	if ctx.Err() != nil {
+		return image.Point{}, ctx.Err()
+	}
+	if st.X == 0 && st.Y == 0 {
+		return image.Point{}, err
+	}
+	if d.target.client.opts.Debug {
+		fmt.Printf("[ui] %v, using recorded point %v\n", err, raw)
+	}
+	return raw, nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
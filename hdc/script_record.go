@@ -0,0 +1,357 @@
+package hdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tapSlop is how far a finger may drift and still count as a tap.
+	tapSlop = 24
+	// longClickTime is the press length that makes a tap a long click.
+	longClickTime = 500 * time.Millisecond
+	// doubleClickGap is the longest pause between the taps of a double click.
+	doubleClickGap = 300 * time.Millisecond
+)
+
+// TouchKind is the phase of a TouchEvent.
+type TouchKind int
+
+const (
+	TouchKindDown TouchKind = iota
+	TouchKindMove
+	TouchKindUp
+)
+
+// TouchEvent is one raw touch sample fed to a GestureRecorder.
+type TouchEvent struct {
+	Kind TouchKind
+	X, Y int
+	// Time defaults to the time Feed is called.
+	Time time.Time
+}
+
+// GestureRecorder turns touch sequences into script steps, resolving where each
+// gesture started against the layout captured at touch down.
+type GestureRecorder struct {
+	d *UiDriver
+
+	mu     sync.Mutex
+	steps  []Step
+	last   time.Time // end of the last step
+	down   bool
+	downAt time.Time
+	start  image.Point
+	root   *LayoutNode
+}
+
+// NewGestureRecorder returns an empty recorder bound to d.
+func (d *UiDriver) NewGestureRecorder() *GestureRecorder { return &GestureRecorder{d: d} }
+
+// TouchDown forwards to UiDriver.TouchDown and records the event.
+func (r *GestureRecorder) TouchDown(ctx context.Context, x, y int) error {
+	// capture before the touch changes the screen
+	r.Feed(ctx, TouchEvent{Kind: TouchKindDown, X: x, Y: y})
+	return r.d.TouchDown(ctx, x, y)
+}
+
+// TouchMove forwards to UiDriver.TouchMove and records the event.
+func (r *GestureRecorder) TouchMove(ctx context.Context, x, y int) error {
+	r.Feed(ctx, TouchEvent{Kind: TouchKindMove, X: x, Y: y})
+	return r.d.TouchMove(ctx, x, y)
+}
+
+// TouchUp forwards to UiDriver.TouchUp and records the gesture.
+func (r *GestureRecorder) TouchUp(ctx context.Context, x, y int) error {
+	err := r.d.TouchUp(ctx, x, y)
+	r.Feed(ctx, TouchEvent{Kind: TouchKindUp, X: x, Y: y})
+	return err
+}
+
+// InputText types into the focused component and records an input step.
+func (r *GestureRecorder) InputText(ctx context.Context, text string) error {
+	if err := r.d.InputTextWith(ctx, text, InputTextOptions{}); err != nil {
+		return err
+	}
+	now := time.Now()
+	r.add(Step{Action: StepInput, Text: text, Desc: fmt.Sprintf("input %q", text)}, now, now)
+	return nil
+}
+
+// Back presses back and records it.
+func (r *GestureRecorder) Back(ctx context.Context) error {
+	if err := r.d.PressBack(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	r.add(Step{Action: StepBack, Desc: "back"}, now, now)
+	return nil
+}
+
+// Feed records a touch event without sending it to the device. A touch down
+// captures the layout, so feed it before the device handles the touch.
+func (r *GestureRecorder) Feed(ctx context.Context, ev TouchEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	p := image.Pt(ev.X, ev.Y)
+	switch ev.Kind {
+	case TouchKindDown:
+		root, err := r.d.DumpLayout(ctx)
+		if err != nil && r.d.target.client.opts.Debug {
+			fmt.Printf("[ui] recorder: layout at touch down: %v\n", err)
+		}
+		r.mu.Lock()
+		r.down, r.downAt, r.start, r.root = true, ev.Time, p, root
+		r.mu.Unlock()
+	case TouchKindUp:
+		r.mu.Lock()
+		if !r.down {
+			r.mu.Unlock()
+			return
+		}
+		r.down = false
+		st := gestureStep(r.start, p, ev.Time.Sub(r.downAt))
+		st.X, st.Y = r.start.X, r.start.Y
+		if r.root != nil {
+			st.Target, st.Desc = resolveTarget(r.root, r.start)
+		}
+		st.Desc = describeStep(st)
+		downAt := r.downAt
+		r.mu.Unlock()
+		r.add(st, downAt, ev.Time)
+	}
+}
+
+// add appends st, which ran from start to end, merging two quick taps on one
+// spot into a double click. DelayMs is the pause since the previous step
+// ended, so replay, which waits DelayMs and then runs the gesture for
+// DurationMs, keeps the recorded timing.
+func (r *GestureRecorder) add(st Step, start, end time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.last.IsZero() {
+		gap := start.Sub(r.last)
+		if n := len(r.steps); n > 0 && st.Action == StepClick && gap < doubleClickGap {
+			prev := &r.steps[n-1]
+			if prev.Action == StepClick && near(image.Pt(prev.X, prev.Y), image.Pt(st.X, st.Y)) {
+				prev.Action = StepDoubleClick
+				prev.Desc = describeStep(*prev)
+				r.last = end
+				return
+			}
+		}
+		st.DelayMs = int(max(gap, 0).Milliseconds())
+	}
+	r.last = end
+	r.steps = append(r.steps, st)
+}
+
+// Script returns the steps recorded so far.
+func (r *GestureRecorder) Script() Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Script{Version: 1, Steps: append([]Step(nil), r.steps...)}
+}
+
+func gestureStep(from, to image.Point, held time.Duration) Step {
+	if near(from, to) {
+		if held >= longClickTime {
+			return Step{Action: StepLongClick, DurationMs: int(held.Milliseconds())}
+		}
+		return Step{Action: StepClick}
+	}
+	return Step{Action: StepSwipe, ToX: to.X, ToY: to.Y, DurationMs: int(held.Milliseconds())}
+}
+
+func near(a, b image.Point) bool {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y)) <= tapSlop
+}
+
+// resolveTarget picks a selector for the component under p: the closest node
+// (itself or an ancestor) with an id, key or text, indexed among its matches.
+func resolveTarget(root *LayoutNode, p image.Point) (*Selector, string) {
+	n := root.NodeAt(p)
+	for n != nil && n.ID() == "" && n.Key() == "" && n.Text() == "" {
+		n = n.Parent
+	}
+	if n == nil {
+		return nil, ""
+	}
+	var sel Selector
+	var what string
+	switch {
+	case n.ID() != "":
+		sel.ID, what = n.ID(), fmt.Sprintf("id %q", n.ID())
+	case n.Key() != "":
+		sel.Key, what = n.Key(), fmt.Sprintf("key %q", n.Key())
+	default:
+		sel.Text, sel.Type, what = n.Text(), n.Type(), fmt.Sprintf("text %q", n.Text())
+	}
+	for i, m := range root.FindAll(sel) {
+		if m == n {
+			sel.Index = i
+			break
+		}
+	}
+	return &sel, "component with " + what
+}
+
+func describeStep(st Step) string {
+	what := st.Desc
+	if what == "" {
+		what = fmt.Sprintf("(%d,%d)", st.X, st.Y)
+	}
+	switch st.Action {
+	case StepClick:
+		return "clicked " + what
+	case StepDoubleClick:
+		return "double clicked " + strings.TrimPrefix(what, "clicked ")
+	case StepLongClick:
+		return "long clicked " + what
+	case StepSwipe:
+		return fmt.Sprintf("swiped from %s to (%d,%d)", what, st.ToX, st.ToY)
+	}
+	return string(st.Action) + " " + what
+}
+
+// uiRecordEvent is one JSON line printed by `uitest uiRecord record`.
+type uiRecordEvent struct {
+	OpType   string  `json:"OP_TYPE"`
+	Duration float64 `json:"duration"`
+	Fingers  []struct {
+		X      flexInt `json:"X_POSI"`
+		Y      flexInt `json:"Y_POSI"`
+		X2     flexInt `json:"X2_POSI"`
+		Y2     flexInt `json:"Y2_POSI"`
+		ID     string  `json:"W1_ID"`
+		Text   string  `json:"W1_Text"`
+		Type   string  `json:"W1_Type"`
+		Bounds string  `json:"W1_BOUNDS"`
+	} `json:"fingerList"`
+}
+
+// flexInt decodes numbers that uitest prints as strings.
+type flexInt int
+
+func (f *flexInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = flexInt(v)
+	return nil
+}
+
+// step converts the event, or reports false for events without a replayable gesture.
+func (e uiRecordEvent) step() (Step, bool) {
+	op := strings.ToLower(e.OpType)
+	if strings.Contains(op, "back") {
+		return Step{Action: StepBack, Desc: "back"}, true
+	}
+	if len(e.Fingers) == 0 {
+		return Step{}, false
+	}
+	f := e.Fingers[0]
+	st := Step{X: int(f.X), Y: int(f.Y), DurationMs: int(e.Duration)}
+	switch op {
+	case "click":
+		st.Action = StepClick
+	case "doubleclick":
+		st.Action = StepDoubleClick
+	case "longclick":
+		st.Action = StepLongClick
+	case "swipe", "fling", "drag":
+		st.Action, st.ToX, st.ToY = StepSwipe, int(f.X2), int(f.Y2)
+	default:
+		return Step{}, false
+	}
+	var what string
+	switch {
+	case f.ID != "":
+		st.Target, what = &Selector{ID: f.ID}, fmt.Sprintf("id %q", f.ID)
+	case f.Text != "":
+		st.Target, what = &Selector{Text: f.Text, Type: f.Type}, fmt.Sprintf("text %q", f.Text)
+	}
+	if what != "" {
+		st.Desc = "component with " + what
+	}
+	st.Desc = describeStep(st)
+	return st, true
+}
+
+// RecordDeviceActions runs `uitest uiRecord record` and converts the actions a
+// person performs on the device into steps until ctx ends. onStep, if set, sees
+// every step as it is recorded.
+func (d *UiDriver) RecordDeviceActions(ctx context.Context, onStep func(Step)) (*Script, error) {
+	c, err := d.target.Shell(ctx, "uitest uiRecord record")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		c.Close()
+		// the recorder keeps running on the device after the shell is gone
+		_ = d.shell(context.Background(), "pkill -f 'uitest uiRecord'")
+	}()
+	script := &Script{Version: 1}
+	var buf []byte
+	var last time.Time // end of the previous action
+	for {
+		chunk, err := c.ReadChunk(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return script, nil
+			}
+			return script, err
+		}
+		buf = append(buf, chunk...)
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			line := bytes.TrimSpace(buf[:i])
+			buf = buf[i+1:]
+			if len(line) == 0 || line[0] != '{' {
+				continue
+			}
+			var ev uiRecordEvent
+			if err := json.Unmarshal(line, &ev); err != nil {
+				if d.target.client.opts.Debug {
+					fmt.Printf("[ui] uiRecord: skip %q: %v\n", line, err)
+				}
+				continue
+			}
+			st, ok := ev.step()
+			if !ok {
+				continue
+			}
+			// an action is printed once it ends; it started DurationMs earlier
+			now := time.Now()
+			if !last.IsZero() {
+				gap := now.Sub(last) - time.Duration(st.DurationMs)*time.Millisecond
+				st.DelayMs = int(max(gap, 0).Milliseconds())
+			}
+			last = now
+			script.Steps = append(script.Steps, st)
+			if onStep != nil {
+				onStep(st)
+			}
+		}
+	}
+}
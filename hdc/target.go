@@ -17,10 +17,22 @@ type Target struct {
 	key    string
 }
 
+// Key returns the connect key (serial) of the target.
+func (t *Target) Key() string { return t.key }
+
 type ShellConnection struct{ conn *Connection }
 
 func (s *ShellConnection) ReadAll(ctx context.Context) ([]byte, error) { return s.conn.ReadAll(ctx) }
 
+// ReadChunk returns the next piece of output of a long-running command,
+// io.EOF once it exits or the shell is closed. Any other error means the hdc
+// connection broke.
+func (s *ShellConnection) ReadChunk(ctx context.Context) ([]byte, error) {
+	return s.conn.readChunk(ctx)
+}
+
+func (s *ShellConnection) Close() { s.conn.Close() }
+
 func (t *Target) transport(ctx context.Context) (*Connection, error) {
 	// readiness probe similar to TS implementation
 	if t.client.opts.Debug {
@@ -406,3 +418,22 @@ func (t *Target) Uninstall(ctx context.Context, bundle string) error {
 	fmt.Printf("[hdc uninstall] target=%s ok out=%q\n", t.key, string(out))
 	return nil
 }
+
+// ShellOutput runs command and returns its output.
+func (t *Target) ShellOutput(ctx context.Context, command string) (string, error) {
+	c, err := t.Shell(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	b, err := c.ReadAll(ctx)
+	return string(b), err
+}
+
+// shellOutput runs command and returns its trimmed output.
+func (t *Target) shellOutput(ctx context.Context, command string) (string, error) {
+	out, err := t.ShellOutput(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
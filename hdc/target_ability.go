@@ -0,0 +1,70 @@
+package hdc
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// AbilityInfo identifies a running ability.
+type AbilityInfo struct {
+	Bundle  string
+	Ability string
+}
+
+func (a AbilityInfo) String() string { return a.Bundle + "/" + a.Ability }
+
+// ForegroundAbility returns the ability currently in the foreground, parsed from `aa dump -l`.
+func (t *Target) ForegroundAbility(ctx context.Context) (AbilityInfo, error) {
+	out, err := t.shellOutput(ctx, "aa dump -l")
+	if err != nil {
+		return AbilityInfo{}, err
+	}
+	if info, ok := parseForegroundAbility(out); ok {
+		return info, nil
+	}
+	return AbilityInfo{}, errors.New("no foreground ability found")
+}
+
+// parseForegroundAbility walks mission records and returns the first one in state #FOREGROUND.
+func parseForegroundAbility(s string) (AbilityInfo, bool) {
+	var cur AbilityInfo
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case strings.HasPrefix(l, "AbilityRecord ID"):
+			cur = AbilityInfo{}
+		case strings.HasPrefix(l, "bundle name ["):
+			cur.Bundle = bracketValue(l)
+		case strings.HasPrefix(l, "main name ["):
+			cur.Ability = bracketValue(l)
+		case strings.HasPrefix(l, "state #FOREGROUND"):
+			if cur.Bundle != "" {
+				return cur, true
+			}
+		}
+	}
+	return AbilityInfo{}, false
+}
+
+func bracketValue(l string) string {
+	i := strings.Index(l, "[")
+	j := strings.LastIndex(l, "]")
+	if i < 0 || j <= i {
+		return ""
+	}
+	return l[i+1 : j]
+}
+
+// StartAbility launches ability of bundle with `aa start`.
+func (t *Target) StartAbility(ctx context.Context, bundle, ability string) error {
+	out, err := t.shellOutput(ctx, "aa start -b "+bundle+" -a "+ability)
+	if err != nil {
+		return err
+	}
+	lower := strings.ToLower(out)
+	if strings.Contains(lower, "error") || strings.Contains(lower, "fail") {
+		return errors.New("start ability failed: " + out)
+	}
+	return nil
+}
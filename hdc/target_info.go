@@ -0,0 +1,42 @@
+package hdc
+
+import (
+	"context"
+	"strconv"
+)
+
+// DeviceInfo is the identity of a device, read from its parameters.
+type DeviceInfo struct {
+	Serial       string `json:"serial"`
+	Model        string `json:"model"`
+	Brand        string `json:"brand"`
+	Manufacturer string `json:"manufacturer"`
+	// OSVersion is the full OS name, e.g. "OpenHarmony-5.0.0.31".
+	OSVersion       string `json:"osVersion"`
+	APIVersion      int    `json:"apiVersion"`
+	SoftwareVersion string `json:"softwareVersion"`
+	CPUABI          string `json:"cpuAbi"`
+}
+
+// DeviceInfo reads the model, OS and build of the device with one `param get`.
+func (t *Target) DeviceInfo(ctx context.Context) (DeviceInfo, error) {
+	p, err := t.GetParameters(ctx)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	return deviceInfoFromParams(t.key, p), nil
+}
+
+func deviceInfoFromParams(serial string, p map[string]string) DeviceInfo {
+	api, _ := strconv.Atoi(p["const.ohos.apiversion"])
+	return DeviceInfo{
+		Serial:          serial,
+		Model:           p["const.product.model"],
+		Brand:           p["const.product.brand"],
+		Manufacturer:    p["const.product.manufacturer"],
+		OSVersion:       p["const.ohos.fullname"],
+		APIVersion:      api,
+		SoftwareVersion: p["const.product.software.version"],
+		CPUABI:          p["const.product.cpu.abilist"],
+	}
+}
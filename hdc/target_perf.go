@@ -0,0 +1,15 @@
+package hdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/airhandsome/hdckit-go/perf"
+)
+
+// StartPerfMonitor samples the CPU, PSS memory, threads and frame rate of
+// bundle every interval until ctx ends or the monitor is stopped. Use
+// perf.Start for more options.
+func (t *Target) StartPerfMonitor(ctx context.Context, bundle string, interval time.Duration) (*perf.Monitor, error) {
+	return perf.Start(ctx, t, bundle, perf.Options{Interval: interval})
+}
@@ -0,0 +1,26 @@
+package hdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/airhandsome/hdckit-go/perf"
+)
+
+// BatteryInfo returns the battery level, charging state, temperature and
+// voltage from BatteryService; one shell call, cheap enough to poll.
+func (t *Target) BatteryInfo(ctx context.Context) (perf.BatteryInfo, error) {
+	return perf.ReadBattery(ctx, t)
+}
+
+// ThermalInfo returns the temperature of every thermal zone and the
+// ThermalService level.
+func (t *Target) ThermalInfo(ctx context.Context) (perf.ThermalInfo, error) {
+	return perf.ReadThermal(ctx, t)
+}
+
+// StartPowerMonitor records battery and thermal state every interval until ctx
+// ends or the monitor is stopped.
+func (t *Target) StartPowerMonitor(ctx context.Context, interval time.Duration) (*perf.PowerMonitor, error) {
+	return perf.StartPower(ctx, t, interval, nil)
+}
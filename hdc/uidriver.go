@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"net"
 	"os"
@@ -23,195 +24,213 @@ const (
 
 // UiDriver provides minimal uitest RPC capabilities similar to TS version.
 type UiDriver struct {
-	target        *Target
-	driverName    string
-	port          int
-	conn          *uiRPCConn
-	mu            sync.Mutex
-	sdkVersion    string
-	sdkPath       string
-	needEnsureSDK bool
+	target     *Target
+	driverName string
+	port       int
+	conn       *uiRPCConn
+	connMu     sync.Mutex
+	mu         sync.Mutex
+	stopped    bool
+	opts       UiDriverOptions
+	display    int
+
+	timings StartTimings
+
+	watchMu  sync.Mutex
+	watchers []Watcher
+	watchRun sync.Mutex
+
+	objMu   sync.Mutex
+	objects map[string]*uiRPCConn // live remote object refs and their connection
+
+	// capOpMu serializes starting and stopping the device stream; capMu guards
+	// the fields below and is never held across an RPC.
+	capOpMu     sync.Mutex
+	capMu       sync.Mutex
+	capStream   uint32
+	capScale    float64
+	capDisplay  int
+	capSessions map[*CaptureSession]struct{}
 }
 
 func (d *UiDriver) SetNeedEnsureSDK(needEnsureSDK bool) {
-	d.needEnsureSDK = needEnsureSDK
+	d.opts.EnsureSDK = needEnsureSDK
 }
 
-func (t *Target) CreateUiDriver() *UiDriver { return &UiDriver{target: t} }
+// SetAutoReconnect makes the driver restart the daemon, recreate the forward and
+// the driver, and resume capture sessions as soon as the RPC connection dies.
+// Calls that fail because of the dead connection are retried once.
+func (d *UiDriver) SetAutoReconnect(enabled bool) {
+	d.mu.Lock()
+	d.opts.AutoReconnect = enabled
+	d.mu.Unlock()
+}
+
+func (t *Target) CreateUiDriver() *UiDriver { return t.CreateUiDriverWithOptions(UiDriverOptions{}) }
 
 // SetSdk allows overriding sdk path and version.
-func (d *UiDriver) SetSdk(path, version string) { d.sdkPath = path; d.sdkVersion = version }
+func (d *UiDriver) SetSdk(path, version string) {
+	d.opts.AgentPath = path
+	if version != "" {
+		d.opts.AgentVersion = version
+	}
+}
 
 func (d *UiDriver) Start(ctx context.Context) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	d.stopped = false
+	return d.start(ctx)
+}
+
+// start brings up the uitest daemon and creates the driver; d.mu must be held.
+func (d *UiDriver) start(ctx context.Context) error {
 	if d.conn != nil {
-		return nil
+		if d.conn.Alive() {
+			return nil
+		}
+		// connection died: drop it and start over
+		d.conn.Close()
+		d.setSession(nil, "")
 	}
 	if d.target.client.opts.Debug {
 		fmt.Printf("[ui] start target=%s\n", d.target.key)
 	}
+	tm := StartTimings{}
+	begin := time.Now()
+	defer func() {
+		tm.Total = time.Since(begin)
+		d.timings = tm
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] start timings %s\n", tm)
+		}
+	}()
 	// enable test mode
+	phase := time.Now()
 	if err := d.shell(ctx, "param set persist.ace.testmode.enabled 1"); err != nil {
 		if d.target.client.opts.Debug {
 			fmt.Printf("[ui] enable test mode failed: %v\n", err)
 		}
 	}
+	tm.TestMode = time.Since(phase)
 	// ensure SDK agent
+	phase = time.Now()
 	if err := d.ensureSdk(ctx); err != nil {
 		if d.target.client.opts.Debug {
 			fmt.Println("[ui] ensureSdk failed", err)
 		}
 		return err
 	}
+	tm.SDK = time.Since(phase)
 	// ensure uitest daemon running
-	if err := d.shell(ctx, "uitest start-daemon singleness"); err != nil {
+	phase = time.Now()
+	if err := d.ensureDaemon(ctx, false); err != nil {
 		if d.target.client.opts.Debug {
 			fmt.Printf("[ui] start-daemon failed: %v\n", err)
 		}
 	}
-	// give daemon time to come up similar to TS (slightly longer for slow devices)
-	time.Sleep(3 * time.Second)
-	// ensure forward tcp:8012
-	p, err := d.forwardTcp(ctx, 8012)
+	tm.Daemon = time.Since(phase)
+	// ensure forward to the uitest port (8012)
+	phase = time.Now()
+	p, err := d.forwardTcp(ctx, d.opts.RemotePort)
 	if err != nil {
 		if d.target.client.opts.Debug {
 			fmt.Println("[ui] forwardTcp failed", err)
 		}
 		return err
 	}
-	rpc := &uiRPCConn{}
-	if err := rpc.Connect(ctx, p); err != nil {
-		if d.target.client.opts.Debug {
-			fmt.Println("[ui] rpc.Connect failed", err)
-		}
-		return err
-	}
-	// create driver
-	payload := map[string]any{
-		"module": "com.ohos.devicetest.hypiumApiHelper",
-		"method": "callHypiumApi",
-		"params": map[string]any{
-			"api":          "Driver.create",
-			"this":         nil,
-			"args":         []any{},
-			"message_type": "hypium",
-		},
-	}
-	if d.target.client.opts.Debug {
-		fmt.Printf("[ui] create driver via rpc\n")
-	}
-	res, err := rpc.SendMessage(ctx, payload, 3*time.Second)
+	tm.Forward = time.Since(phase)
+	// connect and create driver; Driver.create doubles as the readiness ping
+	phase = time.Now()
+	rpc, name, attempts, err := d.connectAndCreate(ctx, p, d.opts.CreateTimeout)
+	tm.CreateAttempts += attempts
 	if err != nil {
 		if d.target.client.opts.Debug {
-			fmt.Println("[ui] rpc.SendMessage failed", err)
+			fmt.Println("[ui] create driver failed", err)
 		}
-		// Recovery: remove device agent and resend, restart daemon, reconnect, retry once
-		rpc.Close()
-		// 仅当设备端 agent 缺失或版本过低时才重装
-		needReinstall := true
-		if raw, e := d.catAgent(ctx); e == nil {
-			cur := extractVersion(raw)
-			want := d.sdkVersion
-			if want == "" {
-				want = "1.1.0"
-			}
-			if strings.Contains(raw, "UITEST_AGENT_LIBRARY") && cmpVersion(cur, want) >= 0 {
-				needReinstall = false
-			}
-			if d.target.client.opts.Debug {
-				fmt.Printf("[ui] catAgent ok cur=%q want=%q reinstall=%v\n", cur, want, needReinstall)
-			}
-		} else {
-			if d.target.client.opts.Debug {
-				fmt.Printf("[ui] catAgent failed: %v\n", e)
-			}
+		if ctx.Err() != nil {
+			return err
 		}
-		if needReinstall {
-			_ = d.shell(ctx, "rm /data/local/tmp/agent.so")
-			if d.sdkPath == "" {
-				d.sdkPath = defaultSdkPath()
-			}
-			// 发送带重试
-			var sendErr error
-			for i := 0; i < 3; i++ {
-				sendErr = d.target.SendFile(ctx, d.sdkPath, "/data/local/tmp/agent.so")
-				if sendErr == nil {
-					break
-				}
-				if d.target.client.opts.Debug {
-					fmt.Printf("[ui] send agent retry %d failed: %v\n", i+1, sendErr)
-				}
-				time.Sleep(500 * time.Millisecond)
-			}
-			if sendErr != nil {
-				return fmt.Errorf("reinstall agent failed: %w", sendErr)
-			}
+		// Recovery: reinstall agent if needed, restart daemon, retry
+		if err := d.recoverAgent(ctx); err != nil {
+			return err
 		}
-		_ = d.shell(ctx, "uitest start-daemon singleness")
-		time.Sleep(3 * time.Second)
-		rpc = &uiRPCConn{}
-		if err2 := rpc.Connect(ctx, p); err2 != nil {
-			return err2
+		if err := d.ensureDaemon(ctx, true); err != nil && d.target.client.opts.Debug {
+			fmt.Printf("[ui] restart daemon failed: %v\n", err)
 		}
-		res, err = rpc.SendMessage(ctx, payload, 3*time.Second)
+		rpc, name, attempts, err = d.connectAndCreate(ctx, p, d.opts.CreateTimeout)
+		tm.CreateAttempts += attempts
 		if err != nil {
-			rpc.Close()
 			return err
 		}
 	}
-	if s, ok := res.(string); ok {
-		d.driverName = s
-	} else {
-		rpc.Close()
-		fmt.Println("invalid create response")
-		return errors.New("invalid create response")
-	}
-	d.conn = rpc
+	tm.Create = time.Since(phase)
+	d.setSession(rpc, name)
 	d.port = p
+	rpc.OnClose(func(err error) { d.onConnLost(rpc, err) })
 	return nil
 }
 
-func (d *UiDriver) Stop() {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	if d.conn != nil {
-		d.conn.Close()
-		d.conn = nil
+// recoverAgent re-pushes the device agent when it is missing or older than wanted.
+func (d *UiDriver) recoverAgent(ctx context.Context) error {
+	// 仅当设备端 agent 缺失或版本过低时才重装
+	needReinstall := true
+	if raw, e := d.catAgent(ctx); e == nil {
+		cur := extractVersion(raw)
+		want := d.opts.AgentVersion
+		if strings.Contains(raw, "UITEST_AGENT_LIBRARY") && cmpVersion(cur, want) >= 0 {
+			needReinstall = false
+		}
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] catAgent ok cur=%q want=%q reinstall=%v\n", cur, want, needReinstall)
+		}
+	} else {
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] catAgent failed: %v\n", e)
+		}
 	}
-	// best-effort kill uitest daemon
-	_ = d.shell(context.Background(), "sh -c 'pidof uitest && kill -9 $(pidof uitest)'")
-}
-
-func (d *UiDriver) GetDisplaySize(ctx context.Context) (map[string]any, error) {
-	if err := d.ensure(ctx); err != nil {
-		return nil, err
+	if !needReinstall {
+		return nil
 	}
-	res, err := d.call(ctx, "CtrlCmd", "getDisplaySize", nil)
-	if err != nil {
-		return nil, err
+	_ = d.shell(ctx, "rm "+d.opts.DeviceAgentPath)
+	if d.opts.AgentPath == "" {
+		d.opts.AgentPath = defaultSdkPath()
+	}
+	// 发送带重试
+	var sendErr error
+	for i := 0; i < 3; i++ {
+		sendErr = d.target.SendFile(ctx, d.opts.AgentPath, d.opts.DeviceAgentPath)
+		if sendErr == nil {
+			break
+		}
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] send agent retry %d failed: %v\n", i+1, sendErr)
+		}
+		time.Sleep(500 * time.Millisecond)
 	}
-	if m, ok := res.(map[string]any); ok {
-		return m, nil
+	if sendErr != nil {
+		return fmt.Errorf("reinstall agent failed: %w", sendErr)
 	}
-	return nil, errors.New("unexpected result")
+	return nil
+}
+
+func (d *UiDriver) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	conn := d.conn
+	d.setSession(nil, "")
+	d.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+	d.closeCaptureSessions()
+	// best-effort kill uitest daemon
+	d.killDaemon(context.Background())
 }
 
+// InputText taps (x, y) and types text; see InputTextWith for more control.
 func (d *UiDriver) InputText(ctx context.Context, text string, x, y int) error {
-	if err := d.ensure(ctx); err != nil {
-		return err
-	}
-	_, err := d.conn.SendMessage(ctx, map[string]any{
-		"module": "com.ohos.devicetest.hypiumApiHelper",
-		"method": "callHypiumApi",
-		"params": map[string]any{
-			"api":          "Driver.inputText",
-			"this":         d.driverName,
-			"args":         []any{map[string]int{"x": x, "y": y}, text},
-			"message_type": "hypium",
-		},
-	}, 3*time.Second)
-	return err
+	return d.InputTextWith(ctx, text, InputTextOptions{At: &image.Point{X: x, Y: y}})
 }
 
 func (d *UiDriver) CaptureLayout(ctx context.Context) (any, error) {
@@ -224,10 +243,7 @@ func (d *UiDriver) CaptureLayout(ctx context.Context) (any, error) {
 func (d *UiDriver) ensure(ctx context.Context) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if d.conn != nil {
-		return nil
-	}
-	return d.Start(ctx)
+	return d.start(ctx)
 }
 
 func (d *UiDriver) call(ctx context.Context, method, api string, args any) (any, error) {
@@ -239,7 +255,62 @@ func (d *UiDriver) call(ctx context.Context, method, api string, args any) (any,
 			"args": args,
 		},
 	}
-	return d.conn.SendMessage(ctx, payload, 3*time.Second)
+	return d.send(ctx, func(string) any { return payload })
+}
+
+// send delivers a message built for the current driver object. With auto
+// reconnect, a call that hit a dead connection is retried once after recovery.
+func (d *UiDriver) send(ctx context.Context, build func(driverName string) any) (any, error) {
+	res, _, err := d.sendVia(ctx, build)
+	return res, err
+}
+
+// sendVia is send, also returning the connection that carried the request;
+// after a retry that is the new connection.
+func (d *UiDriver) sendVia(ctx context.Context, build func(driverName string) any) (any, *uiRPCConn, error) {
+	res, conn, err := d.sendOnce(ctx, build)
+	if errors.Is(err, ErrRPCClosed) && d.reconnectEnabled() {
+		if e := d.ensure(ctx); e != nil {
+			return nil, conn, err
+		}
+		res, conn, err = d.sendOnce(ctx, build)
+	}
+	return res, conn, err
+}
+
+func (d *UiDriver) sendOnce(ctx context.Context, build func(driverName string) any) (any, *uiRPCConn, error) {
+	return d.sendTimeout(ctx, d.rpcTimeout(ctx), build)
+}
+
+// sendTimeout sends with an explicit timeout; zero waits until ctx ends.
+func (d *UiDriver) sendTimeout(ctx context.Context, timeout time.Duration, build func(driverName string) any) (any, *uiRPCConn, error) {
+	conn, name := d.session()
+	if conn == nil {
+		return nil, nil, &RPCClosedError{Cause: errors.New("driver not started")}
+	}
+	resp, err := conn.SendMessage(ctx, build(name), timeout)
+	return resp.Result, conn, err
+}
+
+// callHypium invokes a hypium api on the driver object.
+func (d *UiDriver) callHypium(ctx context.Context, api string, args ...any) (any, error) {
+	if args == nil {
+		args = []any{}
+	}
+	return d.send(ctx, func(name string) any { return hypiumPayload(api, name, args) })
+}
+
+func hypiumPayload(api string, this any, args []any) map[string]any {
+	return map[string]any{
+		"module": "com.ohos.devicetest.hypiumApiHelper",
+		"method": "callHypiumApi",
+		"params": map[string]any{
+			"api":          api,
+			"this":         this,
+			"args":         args,
+			"message_type": "hypium",
+		},
+	}
 }
 
 func (d *UiDriver) forwardTcp(ctx context.Context, remotePort int) (int, error) {
@@ -285,20 +356,17 @@ func (d *UiDriver) shell(ctx context.Context, cmd string) error {
 
 // ensureSdk checks and pushes uitest agent if needed.
 func (d *UiDriver) ensureSdk(ctx context.Context) error {
-	if !d.needEnsureSDK {
+	if !d.opts.EnsureSDK {
 		return nil
 	}
-	if d.sdkVersion == "" {
-		d.sdkVersion = "1.1.0"
-	}
-	if d.sdkPath == "" {
-		d.sdkPath = defaultSdkPath()
+	if d.opts.AgentPath == "" {
+		d.opts.AgentPath = defaultSdkPath()
 	}
 	// check version on device
 	raw, _ := d.catAgent(ctx)
-	if !strings.Contains(raw, "UITEST_AGENT_LIBRARY") || cmpVersion(extractVersion(raw), d.sdkVersion) < 0 {
-		_ = d.shell(ctx, "rm /data/local/tmp/agent.so")
-		if err := d.target.SendFile(ctx, d.sdkPath, "/data/local/tmp/agent.so"); err != nil {
+	if !strings.Contains(raw, "UITEST_AGENT_LIBRARY") || cmpVersion(extractVersion(raw), d.opts.AgentVersion) < 0 {
+		_ = d.shell(ctx, "rm "+d.opts.DeviceAgentPath)
+		if err := d.target.SendFile(ctx, d.opts.AgentPath, d.opts.DeviceAgentPath); err != nil {
 			return fmt.Errorf("send agent failed: %w", err)
 		}
 	}
@@ -306,7 +374,7 @@ func (d *UiDriver) ensureSdk(ctx context.Context) error {
 }
 
 func (d *UiDriver) catAgent(ctx context.Context) (string, error) {
-	c, err := d.target.Shell(ctx, "cat /data/local/tmp/agent.so | grep -a UITEST_AGENT_LIBRARY")
+	c, err := d.target.Shell(ctx, "cat "+d.opts.DeviceAgentPath+" | grep -a UITEST_AGENT_LIBRARY")
 	if err != nil {
 		return "", err
 	}
@@ -368,12 +436,72 @@ func extractVersion(raw string) string {
 	return ""
 }
 
+// ErrRPCClosed matches (via errors.Is) every error caused by a dead uitest RPC connection.
+var ErrRPCClosed = errors.New("uitest rpc connection closed")
+
+// RPCClosedError is returned for calls on, or pending on, a dead uitest RPC connection.
+type RPCClosedError struct {
+	Cause error
+}
+
+func (e *RPCClosedError) Error() string {
+	if e.Cause == nil {
+		return ErrRPCClosed.Error()
+	}
+	return ErrRPCClosed.Error() + ": " + e.Cause.Error()
+}
+
+func (e *RPCClosedError) Is(target error) bool { return target == ErrRPCClosed }
+func (e *RPCClosedError) Unwrap() error        { return e.Cause }
+
+// RPCException is an exception reported by the uitest agent.
+type RPCException struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCException) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("uitest exception %d: %s", e.Code, e.Message)
+	}
+	return "uitest exception: " + e.Message
+}
+
+// Response is one decoded uitest reply. Result holds the raw bytes when the
+// payload is not JSON.
+type Response struct {
+	Result    any           `json:"result"`
+	Exception *RPCException `json:"exception"`
+}
+
+// Err returns the exception as an error, or nil.
+func (r Response) Err() error {
+	if r.Exception != nil {
+		return r.Exception
+	}
+	return nil
+}
+
+func decodeResponse(payload []byte) Response {
+	var r Response
+	if err := json.Unmarshal(payload, &r); err != nil {
+		return Response{Result: append([]byte(nil), payload...)}
+	}
+	return r
+}
+
 // uiRPCConn implements uitest RPC framing protocol.
 type uiRPCConn struct {
 	c        net.Conn
+	writeMu  sync.Mutex
 	mu       sync.Mutex
-	resolves map[uint32]chan any
+	seq      uint32
+	resolves map[uint32]chan Response
+	subs     map[uint32]func(payload []byte)
 	onMsg    func(session uint32, payload []byte)
+	onClose  func(err error)
+	done     chan struct{}
+	closeErr error
 }
 
 func (u *uiRPCConn) Connect(ctx context.Context, port int) error {
@@ -383,7 +511,9 @@ func (u *uiRPCConn) Connect(ctx context.Context, port int) error {
 		return err
 	}
 	u.c = conn
-	u.resolves = make(map[uint32]chan any)
+	u.resolves = make(map[uint32]chan Response)
+	u.subs = make(map[uint32]func(payload []byte))
+	u.done = make(chan struct{})
 	go u.readLoop()
 	return nil
 }
@@ -394,92 +524,149 @@ func (u *uiRPCConn) Close() {
 	}
 }
 
+// Alive reports whether the read loop is still running.
+func (u *uiRPCConn) Alive() bool {
+	select {
+	case <-u.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Err returns the *RPCClosedError once the connection is dead, nil before.
+func (u *uiRPCConn) Err() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.closeErr
+}
+
+// OnClose registers cb to run once when the connection dies.
+func (u *uiRPCConn) OnClose(cb func(err error)) {
+	u.mu.Lock()
+	u.onClose = cb
+	u.mu.Unlock()
+}
+
+// shutdown marks the connection dead and wakes all pending calls.
+func (u *uiRPCConn) shutdown(cause error) {
+	u.mu.Lock()
+	u.closeErr = &RPCClosedError{Cause: cause}
+	u.resolves = make(map[uint32]chan Response)
+	cb := u.onClose
+	u.mu.Unlock()
+	close(u.done)
+	_ = u.c.Close()
+	if cb != nil {
+		cb(u.closeErr)
+	}
+}
+
 func (u *uiRPCConn) OnMessage(cb func(session uint32, payload []byte)) {
 	u.mu.Lock()
 	u.onMsg = cb
 	u.mu.Unlock()
 }
 
-func (u *uiRPCConn) SendMessage(ctx context.Context, message any, timeout time.Duration) (any, error) {
-	payload, _ := json.Marshal(message)
-	sessionId := uint32(time.Now().UnixNano())
-	sid := make([]byte, 4)
-	binary.BigEndian.PutUint32(sid, sessionId)
-	// frame: header + sessionId + len + payload + tailer
-	var length [4]byte
-	binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
-	frame := make([]byte, 0, len(uiHeader)+8+len(payload)+len(uiTailer))
-	frame = append(frame, []byte(uiHeader)...)
-	frame = append(frame, sid...)
-	frame = append(frame, length[:]...)
-	frame = append(frame, payload...)
-	frame = append(frame, []byte(uiTailer)...)
+// Subscribe routes unsolicited messages for session to fn.
+func (u *uiRPCConn) Subscribe(session uint32, fn func(payload []byte)) {
+	u.mu.Lock()
+	u.subs[session] = fn
+	u.mu.Unlock()
+}
 
-	ch := make(chan any, 1)
+func (u *uiRPCConn) Unsubscribe(session uint32) {
 	u.mu.Lock()
-	u.resolves[sessionId] = ch
+	delete(u.subs, session)
 	u.mu.Unlock()
+}
 
-	if _, err := u.c.Write(frame); err != nil {
-		return nil, err
+// SendMessage sends message and waits for the reply. An exception reported by
+// the agent is returned both in the Response and as a *RPCException error.
+func (u *uiRPCConn) SendMessage(ctx context.Context, message any, timeout time.Duration) (Response, error) {
+	_, resp, err := u.SendMessageWithSession(ctx, message, timeout)
+	return resp, err
+}
+
+// SendMessageWithSession sends and returns (sessionId, response, error).
+func (u *uiRPCConn) SendMessageWithSession(ctx context.Context, message any, timeout time.Duration) (uint32, Response, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return 0, Response{}, err
 	}
+	ch := make(chan Response, 1)
+	u.mu.Lock()
+	if u.closeErr != nil {
+		err := u.closeErr
+		u.mu.Unlock()
+		return 0, Response{}, err
+	}
+	sessionId := u.nextSession()
+	u.resolves[sessionId] = ch
+	u.mu.Unlock()
 
+	if err := u.writeFrame(sessionId, payload); err != nil {
+		u.forget(sessionId)
+		if e := u.Err(); e != nil {
+			return sessionId, Response{}, e
+		}
+		return sessionId, Response{}, err
+	}
+	var timeoutC <-chan time.Time
 	if timeout > 0 {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case resp := <-ch:
-			return resp, nil
-		case <-time.After(timeout):
-			u.mu.Lock()
-			delete(u.resolves, sessionId)
-			u.mu.Unlock()
-			return nil, errors.New("timeout")
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		u.forget(sessionId)
+		return sessionId, Response{}, ctx.Err()
+	case r := <-ch:
+		return sessionId, r, r.Err()
+	case <-u.done:
+		return sessionId, Response{}, u.Err()
+	case <-timeoutC:
+		u.forget(sessionId)
+		return sessionId, Response{}, errors.New("timeout")
+	}
+}
+
+// nextSession returns an id not held by a pending call or a subscribed stream; u.mu must be held.
+func (u *uiRPCConn) nextSession() uint32 {
+	for {
+		u.seq++
+		if u.seq == 0 {
+			continue
 		}
+		if _, ok := u.resolves[u.seq]; ok {
+			continue
+		}
+		if _, ok := u.subs[u.seq]; ok {
+			continue
+		}
+		return u.seq
 	}
-	return <-ch, nil
 }
 
-// SendMessageWithSession sends and returns (sessionId, result, error).
-func (u *uiRPCConn) SendMessageWithSession(ctx context.Context, message any, timeout time.Duration) (uint32, any, error) {
-	payload, _ := json.Marshal(message)
-	sessionId := uint32(time.Now().UnixNano())
-	sid := make([]byte, 4)
-	binary.BigEndian.PutUint32(sid, sessionId)
-	var length [4]byte
-	binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
+// writeFrame writes header + sessionId + len + payload + tailer as one write.
+func (u *uiRPCConn) writeFrame(sessionId uint32, payload []byte) error {
 	frame := make([]byte, 0, len(uiHeader)+8+len(payload)+len(uiTailer))
-	frame = append(frame, []byte(uiHeader)...)
-	frame = append(frame, sid...)
-	frame = append(frame, length[:]...)
+	frame = append(frame, uiHeader...)
+	frame = binary.BigEndian.AppendUint32(frame, sessionId)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
 	frame = append(frame, payload...)
-	frame = append(frame, []byte(uiTailer)...)
+	frame = append(frame, uiTailer...)
+	u.writeMu.Lock()
+	defer u.writeMu.Unlock()
+	_, err := u.c.Write(frame)
+	return err
+}
 
-	ch := make(chan any, 1)
+func (u *uiRPCConn) forget(sessionId uint32) {
 	u.mu.Lock()
-	u.resolves[sessionId] = ch
+	delete(u.resolves, sessionId)
 	u.mu.Unlock()
-
-	if _, err := u.c.Write(frame); err != nil {
-		return sessionId, nil, err
-	}
-	var resp any
-	if timeout > 0 {
-		select {
-		case <-ctx.Done():
-			return sessionId, nil, ctx.Err()
-		case r := <-ch:
-			resp = r
-		case <-time.After(timeout):
-			u.mu.Lock()
-			delete(u.resolves, sessionId)
-			u.mu.Unlock()
-			return sessionId, nil, errors.New("timeout")
-		}
-	} else {
-		resp = <-ch
-	}
-	return sessionId, resp, nil
 }
 
 func (u *uiRPCConn) readLoop() {
@@ -490,6 +677,7 @@ func (u *uiRPCConn) readLoop() {
 	for {
 		n, err := u.c.Read(tmp)
 		if err != nil {
+			u.shutdown(err)
 			return
 		}
 		buf = append(buf, tmp[:n]...)
@@ -514,30 +702,16 @@ func (u *uiRPCConn) readLoop() {
 				buf = buf[:0]
 				break
 			}
-			// try JSON first
-			var result struct {
-				Result    any `json:"result"`
-				Exception *struct {
-					Message string `json:"message"`
-				} `json:"exception"`
-			}
-			var val any
-			if err := json.Unmarshal(payload, &result); err == nil {
-				if result.Exception != nil {
-					val = errors.New(result.Exception.Message)
-				} else {
-					val = result.Result
-				}
-			} else {
-				val = payload
-			}
 			u.mu.Lock()
 			ch := u.resolves[sid]
 			delete(u.resolves, sid)
+			sub := u.subs[sid]
 			cb := u.onMsg
 			u.mu.Unlock()
 			if ch != nil {
-				ch <- val
+				ch <- decodeResponse(payload)
+			} else if sub != nil {
+				sub(payload)
 			} else if cb != nil {
 				cb(sid, payload)
 			}
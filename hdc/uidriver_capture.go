@@ -4,21 +4,295 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// StartCaptureScreen starts screen stream; callback receives raw frame payloads.
-func (d *UiDriver) StartCaptureScreen(ctx context.Context, cb func([]byte), scale float64) (int, error) {
+// CaptureSession is one consumer of the device screen stream.
+//
+// The device only runs a single capture stream, so sessions share it: the
+// first session starts the stream and the last one to stop ends it. Sessions
+// started later reuse the scale of the running stream.
+type CaptureSession struct {
+	d       *UiDriver
+	cb      func([]byte)
+	cbq     chan []byte // frames waiting for cb, nil without a callback
+	frames  chan []byte
+	started time.Time
+
+	mu      sync.Mutex
+	id      int
+	closed  bool
+	ended   time.Time
+	count   atomic.Int64
+	dropped atomic.Int64
+	once    sync.Once
+	stopErr error
+}
+
+// CaptureStats is a snapshot of a session's counters.
+type CaptureStats struct {
+	Frames   int64
+	Dropped  int64
+	Duration time.Duration
+	FPS      float64
+}
+
+// ID returns the protocol session id of the device stream this session reads from.
+// It changes when the stream is resumed after a reconnect.
+func (s *CaptureSession) ID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+func (s *CaptureSession) setID(id int) {
+	s.mu.Lock()
+	s.id = id
+	s.mu.Unlock()
+}
+
+// Frames returns a channel of frames. It is closed when the session stops.
+// When the reader falls behind, the oldest buffered frames are dropped.
+// Frames are shared between sessions and must not be modified.
+func (s *CaptureSession) Frames() <-chan []byte { return s.frames }
+
+// FrameCount returns the number of frames received so far.
+func (s *CaptureSession) FrameCount() int64 { return s.count.Load() }
+
+// FPS returns the average frame rate since the session started.
+func (s *CaptureSession) FPS() float64 { return s.Stats().FPS }
+
+// Stats returns frame counters and the average frame rate.
+func (s *CaptureSession) Stats() CaptureStats {
+	s.mu.Lock()
+	end := s.ended
+	s.mu.Unlock()
+	if end.IsZero() {
+		end = time.Now()
+	}
+	st := CaptureStats{
+		Frames:   s.count.Load(),
+		Dropped:  s.dropped.Load(),
+		Duration: end.Sub(s.started),
+	}
+	if st.Duration > 0 {
+		st.FPS = float64(st.Frames) / st.Duration.Seconds()
+	}
+	return st
+}
+
+// Stop detaches the session and stops the device stream if no other session uses it.
+// It is safe to call more than once and from any goroutine.
+func (s *CaptureSession) Stop(ctx context.Context) error {
+	s.once.Do(func() {
+		s.stopErr = s.d.releaseCapture(ctx, s)
+	})
+	return s.stopErr
+}
+
+func newCaptureSession(d *UiDriver, id int, cb func([]byte)) *CaptureSession {
+	s := &CaptureSession{
+		d:       d,
+		id:      id,
+		cb:      cb,
+		frames:  make(chan []byte, 8),
+		started: time.Now(),
+	}
+	if cb != nil {
+		s.cbq = make(chan []byte, 8)
+		go s.runCallback()
+	}
+	return s
+}
+
+// runCallback calls cb off the RPC read goroutine, so cb may block or call
+// RPCs, including Stop on its own session.
+func (s *CaptureSession) runCallback() {
+	for b := range s.cbq {
+		s.cb(b)
+	}
+}
+
+// deliver runs on the RPC read goroutine and never blocks.
+func (s *CaptureSession) deliver(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.count.Add(1)
+	if s.cbq != nil {
+		// a slow callback skips frames rather than stalling the connection
+		pushDropOldest(s.cbq, b)
+	}
+	if pushDropOldest(s.frames, b) {
+		s.dropped.Add(1)
+	}
+}
+
+// pushDropOldest sends b on ch, dropping the oldest buffered frame when ch is
+// full, and reports whether a frame was dropped.
+func pushDropOldest(ch chan []byte, b []byte) bool {
+	select {
+	case ch <- b:
+		return false
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- b:
+	default:
+	}
+	return true
+}
+
+func (s *CaptureSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.ended = time.Now()
+		close(s.frames)
+		if s.cbq != nil {
+			close(s.cbq)
+		}
+	}
+}
+
+// StartCaptureScreen starts a capture session. cb, if not nil, receives the
+// frames in order on a goroutine of the session; when it falls behind, the
+// oldest waiting frames are skipped. Frames are also available from the
+// session's Frames channel.
+func (d *UiDriver) StartCaptureScreen(ctx context.Context, cb func([]byte), scale float64) (*CaptureSession, error) {
 	if scale <= 0 || scale >= 1 {
 		scale = 0
 	}
 	if err := d.ensure(ctx); err != nil {
-		return 0, err
+		return nil, err
+	}
+	d.capOpMu.Lock()
+	defer d.capOpMu.Unlock()
+	d.capMu.Lock()
+	stream := d.capStream
+	d.capMu.Unlock()
+	if stream == 0 {
+		display := d.Display()
+		sid, conn, err := d.startDeviceCapture(ctx, scale, display)
+		if err != nil {
+			return nil, err
+		}
+		stream = uint32(sid)
+		d.capMu.Lock()
+		d.capStream = stream
+		d.capScale, d.capDisplay = scale, display
+		d.capMu.Unlock()
+		conn.Subscribe(stream, d.dispatchFrame)
+	}
+	s := newCaptureSession(d, int(stream), cb)
+	d.capMu.Lock()
+	if d.capSessions == nil {
+		d.capSessions = make(map[*CaptureSession]struct{})
+	}
+	d.capSessions[s] = struct{}{}
+	d.capMu.Unlock()
+	return s, nil
+}
+
+// StopCaptureScreen stops all capture sessions and the device stream.
+func (d *UiDriver) StopCaptureScreen(ctx context.Context) error {
+	d.capMu.Lock()
+	sessions := make([]*CaptureSession, 0, len(d.capSessions))
+	for s := range d.capSessions {
+		sessions = append(sessions, s)
+	}
+	running := d.capStream != 0
+	d.capMu.Unlock()
+	var err error
+	for _, s := range sessions {
+		if e := s.Stop(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	if !running && len(sessions) == 0 {
+		// nothing tracked locally; still ask the device to stop
+		if e := d.ensure(ctx); e != nil {
+			return e
+		}
+		_, err = d.call(ctx, "Captures", "stopCaptureScreen", nil)
+	}
+	return err
+}
+
+// releaseCapture detaches s and stops the device stream after the last session.
+// capMu is never held across an RPC: dispatchFrame takes it on the read
+// goroutine, which must stay free to read the reply.
+func (d *UiDriver) releaseCapture(ctx context.Context, s *CaptureSession) error {
+	d.capOpMu.Lock()
+	defer d.capOpMu.Unlock()
+	d.capMu.Lock()
+	delete(d.capSessions, s)
+	sid := d.capStream
+	last := len(d.capSessions) == 0 && sid != 0
+	if last {
+		d.capStream = 0
+	}
+	d.capMu.Unlock()
+	s.close()
+	if !last {
+		return nil
+	}
+	conn, _ := d.session()
+	if conn == nil {
+		return nil
+	}
+	conn.Unsubscribe(sid)
+	_, err := d.call(ctx, "Captures", "stopCaptureScreen", nil)
+	return err
+}
+
+// dispatchFrame fans a device frame out to all sessions.
+func (d *UiDriver) dispatchFrame(payload []byte) {
+	// payload aliases the RPC read buffer
+	b := make([]byte, len(payload))
+	copy(b, payload)
+	d.capMu.Lock()
+	sessions := make([]*CaptureSession, 0, len(d.capSessions))
+	for s := range d.capSessions {
+		sessions = append(sessions, s)
+	}
+	d.capMu.Unlock()
+	for _, s := range sessions {
+		s.deliver(b)
+	}
+}
+
+// closeCaptureSessions ends all sessions locally, e.g. when the driver stops.
+func (d *UiDriver) closeCaptureSessions() {
+	d.capMu.Lock()
+	defer d.capMu.Unlock()
+	for s := range d.capSessions {
+		s.close()
 	}
+	d.capSessions = nil
+	d.capStream = 0
+}
+
+// startDeviceCapture asks the device to start streaming and returns the stream
+// session id and the connection that carries the stream, which is a new one
+// when the fallback call reconnected.
+func (d *UiDriver) startDeviceCapture(ctx context.Context, scale float64, display int) (int, *uiRPCConn, error) {
 	opts := map[string]any{}
 	if scale > 0 {
 		opts["scale"] = scale
 	}
+	if display != 0 {
+		opts["displayId"] = display
+	}
 	// Prefer protocol sessionId (robust even when result is boolean)
 	payload := map[string]any{
 		"module": "com.ohos.devicetest.hypiumApiHelper",
@@ -28,46 +302,37 @@ func (d *UiDriver) StartCaptureScreen(ctx context.Context, cb func([]byte), scal
 			"args": map[string]any{"options": opts},
 		},
 	}
-	sidU32, _, err := d.conn.SendMessageWithSession(ctx, payload, 3*time.Second)
+	conn, _ := d.session()
+	if conn == nil {
+		return 0, nil, &RPCClosedError{Cause: errors.New("driver not started")}
+	}
+	sidU32, _, err := conn.SendMessageWithSession(ctx, payload, d.rpcTimeout(ctx))
 	if err == nil {
-		sid := int(sidU32)
-		attachCaptureHandler(d, sid, cb)
-		return sid, nil
+		return int(sidU32), conn, nil
 	}
 	// Fallback to generic call parsing
-	res2, err2 := d.call(ctx, "Captures", "startCaptureScreen", map[string]any{"options": opts})
+	res2, conn, err2 := d.sendVia(ctx, func(string) any { return payload })
 	if err2 != nil {
-		return 0, err2
+		return 0, nil, err2
 	}
 	// direct number
 	if sid, ok := toInt(res2); ok {
-		attachCaptureHandler(d, sid, cb)
-		return sid, nil
+		return sid, conn, nil
 	}
 	// map or nested map
 	if sid, ok := getSessionIdFromAny(res2); ok {
-		attachCaptureHandler(d, sid, cb)
-		return sid, nil
+		return sid, conn, nil
 	}
 	// []byte json
 	if b, ok := res2.([]byte); ok {
 		var m map[string]any
 		if err := json.Unmarshal(b, &m); err == nil {
 			if sid, ok := getSessionIdFromAny(m); ok {
-				attachCaptureHandler(d, sid, cb)
-				return sid, nil
+				return sid, conn, nil
 			}
 		}
 	}
-	return 0, errors.New("unexpected startCaptureScreen result")
-}
-
-func attachCaptureHandler(d *UiDriver, sid int, cb func([]byte)) {
-	d.conn.OnMessage(func(session uint32, payload []byte) {
-		if int(session) == sid {
-			cb(payload)
-		}
-	})
+	return 0, nil, errors.New("unexpected startCaptureScreen result")
 }
 
 func getSessionIdFromAny(v any) (int, bool) {
@@ -91,15 +356,6 @@ func getSessionIdFromAny(v any) (int, bool) {
 	return 0, false
 }
 
-func (d *UiDriver) StopCaptureScreen(ctx context.Context) error {
-	if err := d.ensure(ctx); err != nil {
-		return err
-	}
-	_, err := d.call(ctx, "Captures", "stopCaptureScreen", nil)
-	d.conn.OnMessage(nil)
-	return err
-}
-
 func toInt(v any) (int, bool) {
 	switch x := v.(type) {
 	case float64:
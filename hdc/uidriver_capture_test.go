@@ -0,0 +1,169 @@
+package hdc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUitest answers uitest RPCs on one end of a pipe and streams frames on
+// the session of startCaptureScreen until stopCaptureScreen.
+type fakeUitest struct {
+	c       net.Conn
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	stream chan struct{} // closed to end the running stream
+}
+
+func (f *fakeUitest) write(sid uint32, payload []byte) error {
+	frame := append([]byte(uiHeader), binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, sid), uint32(len(payload)))...)
+	frame = append(append(frame, payload...), uiTailer...)
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	_, err := f.c.Write(frame)
+	return err
+}
+
+func (f *fakeUitest) serve() {
+	var buf []byte
+	tmp := make([]byte, 4096)
+	for {
+		n, err := f.c.Read(tmp)
+		if err != nil {
+			f.stopStream()
+			return
+		}
+		buf = append(buf, tmp[:n]...)
+		for len(buf) >= len(uiHeader)+8 {
+			sid := binary.BigEndian.Uint32(buf[len(uiHeader):])
+			total := len(uiHeader) + 8 + int(binary.BigEndian.Uint32(buf[len(uiHeader)+4:])) + len(uiTailer)
+			if len(buf) < total {
+				break
+			}
+			var req struct {
+				Params struct {
+					API string `json:"api"`
+				} `json:"params"`
+			}
+			_ = json.Unmarshal(buf[len(uiHeader)+8:total-len(uiTailer)], &req)
+			buf = buf[total:]
+			switch req.Params.API {
+			case "startCaptureScreen":
+				f.startStream(sid)
+			case "stopCaptureScreen":
+				f.stopStream()
+			}
+			go f.write(sid, []byte(`{"result":true}`))
+		}
+	}
+}
+
+func (f *fakeUitest) startStream(sid uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stop := make(chan struct{})
+	f.stream = stop
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				if f.write(sid, []byte("frame")) != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (f *fakeUitest) stopStream() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stream != nil {
+		close(f.stream)
+		f.stream = nil
+	}
+}
+
+func newFakeDriver(t *testing.T) *UiDriver {
+	t.Helper()
+	client, device := net.Pipe()
+	go (&fakeUitest{c: device}).serve()
+	conn := &uiRPCConn{
+		c:        client,
+		resolves: make(map[uint32]chan Response),
+		subs:     make(map[uint32]func(payload []byte)),
+		done:     make(chan struct{}),
+	}
+	go conn.readLoop()
+	d := NewClient(Options{}).Target("fake").CreateUiDriverWithOptions(UiDriverOptions{Timeout: 2 * time.Second})
+	d.setSession(conn, "Driver#0")
+	t.Cleanup(func() { conn.Close(); device.Close() })
+	return d
+}
+
+func TestCaptureSessionsStopConcurrently(t *testing.T) {
+	d := newFakeDriver(t)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		a, err := d.StartCaptureScreen(ctx, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := d.StartCaptureScreen(ctx, func([]byte) {}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		for _, s := range []*CaptureSession{a, b} {
+			wg.Add(1)
+			go func(s *CaptureSession) {
+				defer wg.Done()
+				errs <- s.Stop(ctx)
+			}(s)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("round %d: stop: %v", i, err)
+			}
+		}
+		// closed by Stop once the buffered frames are read
+		for range a.Frames() {
+		}
+	}
+}
+
+func TestCaptureCallbackStopsOwnSession(t *testing.T) {
+	d := newFakeDriver(t)
+	ctx := context.Background()
+	stopped := make(chan error, 1)
+	var s *CaptureSession
+	var once sync.Once
+	ready := make(chan struct{})
+	s, err := d.StartCaptureScreen(ctx, func([]byte) {
+		<-ready
+		once.Do(func() { stopped <- s.Stop(ctx) })
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ready)
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("stop from callback: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stop from callback did not return")
+	}
+}
@@ -0,0 +1,249 @@
+package hdc
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DisplayRotation is the uitest display rotation, clockwise.
+type DisplayRotation int
+
+const (
+	Rotation0 DisplayRotation = iota
+	Rotation90
+	Rotation180
+	Rotation270
+)
+
+func (r DisplayRotation) String() string {
+	if r >= Rotation0 && r <= Rotation270 {
+		return strconv.Itoa(int(r)*90) + "°"
+	}
+	return fmt.Sprintf("DisplayRotation(%d)", int(r))
+}
+
+// ParseRotation accepts 0, 90, 180 or 270.
+func ParseRotation(s string) (DisplayRotation, error) {
+	deg, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "°"))
+	if err != nil || deg%90 != 0 || deg < 0 || deg > 270 {
+		return 0, fmt.Errorf("invalid rotation %q (want 0, 90, 180 or 270)", s)
+	}
+	return DisplayRotation(deg / 90), nil
+}
+
+var (
+	rePowerState = regexp.MustCompile(`Current State:\s*(\w+)`)
+	reDensityDpi = regexp.MustCompile(`(?i)density\s*dpi\s*[:=]\s*(\d+)`)
+)
+
+// GetDisplayRotation returns the current rotation via hypium Driver.getDisplayRotation.
+func (d *UiDriver) GetDisplayRotation(ctx context.Context) (DisplayRotation, error) {
+	return Invoke[DisplayRotation](ctx, d, "Driver.getDisplayRotation", d.Driver())
+}
+
+// SetDisplayRotation rotates the display via hypium Driver.setDisplayRotation.
+func (d *UiDriver) SetDisplayRotation(ctx context.Context, r DisplayRotation) error {
+	_, err := d.CallHypium(ctx, "Driver.setDisplayRotation", d.Driver(), int(r))
+	return err
+}
+
+// SetRotationLock stops (locked) or allows automatic rotation.
+func (d *UiDriver) SetRotationLock(ctx context.Context, locked bool) error {
+	_, err := d.CallHypium(ctx, "Driver.setDisplayRotationEnabled", d.Driver(), !locked)
+	return err
+}
+
+// IsScreenOn reports whether the power state is AWAKE, from hidumper PowerManagerService.
+func (d *UiDriver) IsScreenOn(ctx context.Context) (bool, error) {
+	out, err := d.target.shellOutput(ctx, "hidumper -s PowerManagerService -a -s")
+	if err != nil {
+		return false, err
+	}
+	m := rePowerState.FindStringSubmatch(out)
+	if m == nil {
+		return false, fmt.Errorf("power state not found in hidumper output")
+	}
+	return m[1] == "AWAKE", nil
+}
+
+// WakeUpDisplay turns the screen on via hypium Driver.wakeUpDisplay, falling
+// back to power-shell.
+func (d *UiDriver) WakeUpDisplay(ctx context.Context) error {
+	if _, err := d.CallHypium(ctx, "Driver.wakeUpDisplay", d.Driver()); err == nil {
+		return nil
+	} else if d.target.client.opts.Debug {
+		fmt.Printf("[ui] wakeUpDisplay failed: %v, fallback to power-shell\n", err)
+	}
+	return d.shell(ctx, "power-shell wakeup")
+}
+
+// Unlock wakes the screen, swipes the lock screen up and, when pin is not
+// empty, taps its digits on the keypad found in the layout: the innermost
+// node holding all ten digit keys.
+func (d *UiDriver) Unlock(ctx context.Context, pin string) error {
+	if on, err := d.IsScreenOn(ctx); err != nil || !on {
+		if err := d.WakeUpDisplay(ctx); err != nil {
+			return err
+		}
+	}
+	size, err := d.GetDisplaySize(ctx)
+	if err != nil {
+		return err
+	}
+	x := size.Width / 2
+	from, to := image.Pt(x, size.Height*4/5), image.Pt(x, size.Height/5)
+	if err := d.swipe(ctx, from, to, 300*time.Millisecond); err != nil {
+		return err
+	}
+	if pin == "" {
+		return nil
+	}
+	// the keypad slides in after the swipe
+	var root *LayoutNode
+	padShown := NewCondition("pin pad shown", func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		r, err := d.DumpLayout(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		root = r
+		return pinPad(r) != nil, nil, nil
+	})
+	wctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := d.WaitUntil(wctx, padShown, 200*time.Millisecond); err != nil {
+		return fmt.Errorf("unlock: pin pad not shown: %w", err)
+	}
+	keys, err := pinKeys(root, pin)
+	if err != nil {
+		return fmt.Errorf("unlock: %w", err)
+	}
+	for _, p := range keys {
+		if err := d.tap(ctx, p.X, p.Y); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pinPad returns the innermost node holding a key for every digit, i.e. the
+// keypad, so digits elsewhere on the lock screen (clock, hints, counters)
+// are never taken for keys. It is nil when no keypad is shown.
+func pinPad(n *LayoutNode) *LayoutNode {
+	if !hasAllDigits(n) {
+		return nil
+	}
+	for _, c := range n.Children {
+		if p := pinPad(c); p != nil {
+			return p
+		}
+	}
+	return n
+}
+
+func hasAllDigits(n *LayoutNode) bool {
+	var seen [10]bool
+	left := len(seen)
+	n.Walk(func(c *LayoutNode) bool {
+		if t := strings.TrimSpace(c.Text()); len(t) == 1 && t[0] >= '0' && t[0] <= '9' && !seen[t[0]-'0'] {
+			seen[t[0]-'0'] = true
+			left--
+		}
+		return left > 0
+	})
+	return left == 0
+}
+
+// pinKeys returns where to tap for each digit of pin on the keypad in root.
+func pinKeys(root *LayoutNode, pin string) ([]image.Point, error) {
+	pad := pinPad(root)
+	if pad == nil {
+		return nil, fmt.Errorf("%w: pin pad", ErrComponentNotFound)
+	}
+	keys := make([]image.Point, 0, len(pin))
+	for _, c := range pin {
+		var key *LayoutNode
+		pad.Walk(func(n *LayoutNode) bool {
+			if strings.TrimSpace(n.Text()) == string(c) {
+				key = n
+			}
+			return key == nil
+		})
+		if key == nil {
+			return nil, fmt.Errorf("%w: pin key %q", ErrComponentNotFound, c)
+		}
+		keys = append(keys, key.Center())
+	}
+	return keys, nil
+}
+
+// GetDisplayDensity returns the display density in dpi via hypium
+// Driver.getDisplayDensity, falling back to hidumper and const.product.densitydpi.
+func (d *UiDriver) GetDisplayDensity(ctx context.Context) (int, error) {
+	res, err := d.CallHypium(ctx, "Driver.getDisplayDensity", d.Driver())
+	if err == nil {
+		if v, ok := toInt(res); ok && v > 0 {
+			return v, nil
+		}
+		if m, ok := res.(map[string]any); ok {
+			if v, ok := toInt(m["x"]); ok && v > 0 {
+				return v, nil
+			}
+		}
+	} else if d.target.client.opts.Debug {
+		fmt.Printf("[ui] getDisplayDensity failed: %v, fallback to hidumper\n", err)
+	}
+	out, err := d.target.shellOutput(ctx, "hidumper -s DisplayManagerService -a -a")
+	if err == nil {
+		if m := reDensityDpi.FindStringSubmatch(out); m != nil {
+			v, _ := strconv.Atoi(m[1])
+			return v, nil
+		}
+	}
+	out, err = d.target.shellOutput(ctx, "param get const.product.densitydpi")
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("display density not found: %q", out)
+	}
+	return v, nil
+}
+
+// tap presses and releases at (x, y) through Gestures.
+func (d *UiDriver) tap(ctx context.Context, x, y int) error {
+	if err := d.TouchDown(ctx, x, y); err != nil {
+		return err
+	}
+	return d.TouchUp(ctx, x, y)
+}
+
+// swipe drags from one point to another in small steps through Gestures.
+func (d *UiDriver) swipe(ctx context.Context, from, to image.Point, dur time.Duration) error {
+	const steps = 10
+	if err := d.TouchDown(ctx, from.X, from.Y); err != nil {
+		return err
+	}
+	for i := 1; i <= steps; i++ {
+		t := time.NewTimer(dur / steps)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			_ = d.TouchUp(context.Background(), from.X, from.Y)
+			return ctx.Err()
+		case <-t.C:
+		}
+		x := from.X + (to.X-from.X)*i/steps
+		y := from.Y + (to.Y-from.Y)*i/steps
+		if err := d.TouchMove(ctx, x, y); err != nil {
+			_ = d.TouchUp(context.Background(), x, y)
+			return err
+		}
+	}
+	return d.TouchUp(ctx, to.X, to.Y)
+}
@@ -0,0 +1,69 @@
+package hdc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+// lockScreenLayout is a pin lock screen in the CaptureLayout format. A
+// notification badge "1" and the clock digits come before the keypad in
+// depth-first order.
+func lockScreenLayout(withPad bool) string {
+	var keys []string
+	if withPad {
+		// keypad rows 1-2-3 / 4-5-6 / 7-8-9 / 0, keys 300x150 from (90,1200)
+		for i, k := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"} {
+			col, row := i%3, i/3
+			if k == "0" {
+				col = 1
+			}
+			x, y := 90+col*300, 1200+row*150
+			keys = append(keys, fmt.Sprintf(`{"attributes":{"type":"Button","clickable":"true","bounds":"[%d,%d][%d,%d]"},
+				"children":[{"attributes":{"type":"Text","text":%q,"bounds":"[%d,%d][%d,%d]"},"children":[]}]}`,
+				x, y, x+300, y+150, k, x+120, y+45, x+180, y+105))
+		}
+	}
+	return `{"attributes":{"type":"root","bounds":"[0,0][1080,2400]"},"children":[
+		{"attributes":{"type":"Column","bounds":"[0,0][1080,600]"},"children":[
+			{"attributes":{"type":"Text","text":"1","id":"notification_badge","bounds":"[980,40][1020,80]"},"children":[]},
+			{"attributes":{"type":"Text","text":"10:21","bounds":"[340,200][740,360]"},"children":[]},
+			{"attributes":{"type":"Text","text":"2","bounds":"[500,380][540,420]"},"children":[]}
+		]},
+		{"attributes":{"type":"Text","text":"Enter PIN","bounds":"[390,1000][690,1060]"},"children":[]},
+		{"attributes":{"type":"Grid","id":"pinKeypad","bounds":"[90,1200][990,1800]"},"children":[` + strings.Join(keys, ",") + `]}
+	]}`
+}
+
+func TestPinKeysIgnoreDigitsOutsideKeypad(t *testing.T) {
+	root, err := ParseLayout(lockScreenLayout(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pad := pinPad(root); pad == nil || pad.ID() != "pinKeypad" {
+		t.Fatalf("pinPad = %v, want the keypad grid", pad)
+	}
+	got, err := pinKeys(root, "1290")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Point{{240, 1275}, {540, 1275}, {840, 1575}, {540, 1725}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestPinKeysWithoutKeypad(t *testing.T) {
+	root, err := ParseLayout(lockScreenLayout(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pad := pinPad(root); pad != nil {
+		t.Fatalf("pinPad = %v, want nil", pad.Attributes)
+	}
+	if _, err := pinKeys(root, "1"); !errors.Is(err, ErrComponentNotFound) {
+		t.Fatalf("err = %v, want ErrComponentNotFound", err)
+	}
+}
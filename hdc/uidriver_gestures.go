@@ -2,13 +2,15 @@ package hdc
 
 import (
 	"context"
+	"image"
+	"time"
 )
 
 func (d *UiDriver) TouchDown(ctx context.Context, x, y int) error {
 	if err := d.ensure(ctx); err != nil {
 		return err
 	}
-	_, err := d.call(ctx, "Gestures", "touchDown", map[string]int{"x": x, "y": y})
+	_, err := d.call(ctx, "Gestures", "touchDown", d.point(x, y))
 	return err
 }
 
@@ -16,7 +18,7 @@ func (d *UiDriver) TouchMove(ctx context.Context, x, y int) error {
 	if err := d.ensure(ctx); err != nil {
 		return err
 	}
-	_, err := d.call(ctx, "Gestures", "touchMove", map[string]int{"x": x, "y": y})
+	_, err := d.call(ctx, "Gestures", "touchMove", d.point(x, y))
 	return err
 }
 
@@ -24,6 +26,42 @@ func (d *UiDriver) TouchUp(ctx context.Context, x, y int) error {
 	if err := d.ensure(ctx); err != nil {
 		return err
 	}
-	_, err := d.call(ctx, "Gestures", "touchUp", map[string]int{"x": x, "y": y})
+	_, err := d.call(ctx, "Gestures", "touchUp", d.point(x, y))
+	return err
+}
+
+// Click taps at (x, y) via hypium Driver.click.
+func (d *UiDriver) Click(ctx context.Context, x, y int) error {
+	if err := d.ensure(ctx); err != nil {
+		return err
+	}
+	args := []any{x, y}
+	if id := d.Display(); id != 0 {
+		args = append(args, id)
+	}
+	_, err := d.callHypium(ctx, "Driver.click", args...)
+	return err
+}
+
+// point builds a uitest Point on the selected display.
+func (d *UiDriver) point(x, y int) map[string]int {
+	p := map[string]int{"x": x, "y": y}
+	if id := d.Display(); id != 0 {
+		p["displayId"] = id
+	}
+	return p
+}
+
+// Swipe drags from (x1, y1) to (x2, y2) over dur (default 300ms).
+func (d *UiDriver) Swipe(ctx context.Context, x1, y1, x2, y2 int, dur time.Duration) error {
+	if dur <= 0 {
+		dur = 300 * time.Millisecond
+	}
+	return d.swipe(ctx, image.Pt(x1, y1), image.Pt(x2, y2), dur)
+}
+
+// PressBack presses the back key.
+func (d *UiDriver) PressBack(ctx context.Context) error {
+	_, err := d.callHypium(ctx, "Driver.pressBack")
 	return err
 }
@@ -0,0 +1,37 @@
+package hdc
+
+import (
+	"context"
+	"image"
+
+	"github.com/airhandsome/hdckit-go/vision"
+)
+
+// FindImage takes a screenshot and locates template on it.
+// It returns vision.ErrNotFound when nothing reaches opts.Threshold.
+func (d *UiDriver) FindImage(ctx context.Context, template image.Image, opts vision.MatchOptions) (vision.Match, error) {
+	screen, err := d.ScreenshotImage(ctx, image.Rectangle{})
+	if err != nil {
+		return vision.Match{}, err
+	}
+	return vision.Find(screen, template, opts)
+}
+
+// ClickImage finds template on screen and taps its center.
+func (d *UiDriver) ClickImage(ctx context.Context, template image.Image, opts vision.MatchOptions) (vision.Match, error) {
+	m, err := d.FindImage(ctx, template, opts)
+	if err != nil {
+		return m, err
+	}
+	c := m.Center()
+	return m, d.Click(ctx, c.X, c.Y)
+}
+
+// CompareScreen diffs the current screen against expected, which must have the screen size.
+func (d *UiDriver) CompareScreen(ctx context.Context, expected image.Image, opts vision.DiffOptions) (vision.DiffResult, error) {
+	screen, err := d.ScreenshotImage(ctx, image.Rectangle{})
+	if err != nil {
+		return vision.DiffResult{}, err
+	}
+	return vision.Diff(screen, expected, opts)
+}
@@ -0,0 +1,170 @@
+package hdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+const (
+	keyCodeCtrlLeft = 2072
+	keyCodeV        = 2038
+)
+
+// onSeed is the root On builder of the uitest agent.
+const onSeed = "On#seed"
+
+// InputTextOptions controls where and how InputTextWith types.
+type InputTextOptions struct {
+	// Component receives the text. It takes precedence over At.
+	Component *RemoteObject
+	// At is tapped before typing. Without Component and At, the focused component is used.
+	At *image.Point
+	// Clear removes the existing text first.
+	Clear bool
+	// Append keeps the existing text and adds to its end.
+	Append bool
+	// Paste inserts the text through the clipboard instead of injecting keys.
+	// It is switched on automatically for text the key injector cannot type (CJK, emoji).
+	Paste bool
+}
+
+// InputTextWith types text as described by opts. When injection fails it falls
+// back to pasting, then to the uitest shell input method.
+func (d *UiDriver) InputTextWith(ctx context.Context, text string, opts InputTextOptions) error {
+	if err := d.ensure(ctx); err != nil {
+		return err
+	}
+	if !opts.Paste && !injectable(text) {
+		opts.Paste = true
+	}
+	comp := opts.Component
+	at := opts.At
+	if comp == nil && (at == nil || opts.Clear) {
+		if at != nil {
+			if err := d.Click(ctx, at.X, at.Y); err != nil {
+				return err
+			}
+		}
+		c, err := d.focusedComponent(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Release(ctx)
+		comp = c
+	}
+	if comp != nil {
+		if opts.Clear {
+			if _, err := comp.Call(ctx, "Component.clearText"); err != nil {
+				return err
+			}
+		}
+		if !opts.Append && !opts.Paste {
+			_, err := comp.Call(ctx, "Component.inputText", text)
+			if err == nil {
+				return nil
+			}
+			if d.target.client.opts.Debug {
+				fmt.Printf("[ui] Component.inputText failed: %v, fallback to paste\n", err)
+			}
+			opts.Paste = true
+		}
+		r, err := Invoke[hypiumRect](ctx, d, "Component.getBounds", comp)
+		if err != nil {
+			return err
+		}
+		c := r.rectangle()
+		at = &image.Point{X: (c.Min.X + c.Max.X) / 2, Y: (c.Min.Y + c.Max.Y) / 2}
+	}
+	err := d.inputAt(ctx, *at, text, opts.Paste, opts.Append)
+	if err == nil {
+		return nil
+	}
+	if d.target.client.opts.Debug {
+		fmt.Printf("[ui] Driver.inputText failed: %v, fallback\n", err)
+	}
+	perr := d.pasteText(ctx, text)
+	if perr == nil {
+		return nil
+	}
+	if serr := d.shellInput(ctx, *at, text); serr != nil {
+		return fmt.Errorf("input text: %w (paste: %v, shell: %v)", err, perr, serr)
+	}
+	return nil
+}
+
+// inputAt types at p via Driver.inputText with an InputTextMode.
+func (d *UiDriver) inputAt(ctx context.Context, p image.Point, text string, paste, addition bool) error {
+	args := []any{d.point(p.X, p.Y), text}
+	if paste || addition {
+		args = append(args, map[string]bool{"paste": paste, "addition": addition})
+	}
+	_, err := d.callHypium(ctx, "Driver.inputText", args...)
+	return err
+}
+
+// pasteText puts text on the clipboard and presses Ctrl+V in the focused component.
+func (d *UiDriver) pasteText(ctx context.Context, text string) error {
+	if err := d.SetClipboard(ctx, text); err != nil {
+		return err
+	}
+	_, err := d.callHypium(ctx, "Driver.triggerCombineKeys", keyCodeCtrlLeft, keyCodeV)
+	return err
+}
+
+// shellInput uses the uitest command line input method, the last resort.
+func (d *UiDriver) shellInput(ctx context.Context, p image.Point, text string) error {
+	out, err := d.target.shellOutput(ctx, fmt.Sprintf("uitest uiInput inputText %d %d %s", p.X, p.Y, shellQuote(text)))
+	if err != nil {
+		return err
+	}
+	if strings.Contains(strings.ToLower(out), "error") || strings.Contains(strings.ToLower(out), "fail") {
+		return errors.New("uitest uiInput: " + out)
+	}
+	return nil
+}
+
+// focusedComponent returns the component that has focus.
+func (d *UiDriver) focusedComponent(ctx context.Context) (*RemoteObject, error) {
+	on, err := Invoke[*RemoteObject](ctx, d, "On.focused", onSeed, true)
+	if err != nil {
+		return nil, err
+	}
+	defer on.Release(ctx)
+	c, err := Invoke[*RemoteObject](ctx, d, "Driver.findComponent", d.Driver(), on)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%w: no focused component", ErrComponentNotFound)
+	}
+	return c, nil
+}
+
+// SetClipboard sets the device clipboard to plain text. uitest builds without the
+// clipboard apis report an *RPCException.
+func (d *UiDriver) SetClipboard(ctx context.Context, text string) error {
+	_, err := d.CallHypium(ctx, "Driver.setClipboard", d.Driver(), text)
+	return err
+}
+
+// GetClipboard returns the plain text on the device clipboard.
+func (d *UiDriver) GetClipboard(ctx context.Context) (string, error) {
+	return Invoke[string](ctx, d, "Driver.getClipboard", d.Driver())
+}
+
+// injectable reports whether the key injector can type every rune of s.
+func injectable(s string) bool {
+	for _, r := range s {
+		if r > 0x7e || (r < 0x20 && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
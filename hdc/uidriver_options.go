@@ -0,0 +1,91 @@
+package hdc
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultRPCTimeout      = 3 * time.Second
+	defaultCreateTimeout   = 8 * time.Second
+	defaultAgentVersion    = "1.1.0"
+	defaultDeviceAgentPath = "/data/local/tmp/agent.so"
+	defaultUitestPort      = 8012
+)
+
+// UiDriverOptions configures a UiDriver. Zero values select the defaults.
+type UiDriverOptions struct {
+	// Timeout bounds a single RPC when the context has no deadline (default 3s).
+	// A context deadline always takes precedence, longer or shorter.
+	Timeout time.Duration
+	// CreateTimeout bounds the connect + Driver.create retries in Start (default 8s).
+	CreateTimeout time.Duration
+	// AgentPath is the local agent library (default uitestkit_sdk/uitest_agent_v1.1.0.so
+	// in the working dir or its parent).
+	AgentPath string
+	// AgentVersion is the minimum agent version accepted on the device (default 1.1.0).
+	AgentVersion string
+	// DeviceAgentPath is where the agent is pushed (default /data/local/tmp/agent.so).
+	DeviceAgentPath string
+	// RemotePort is the uitest port on the device (default 8012).
+	RemotePort int
+	// EnsureSDK pushes the agent when it is missing or outdated on the device.
+	EnsureSDK bool
+	// AutoReconnect restarts the driver when the RPC connection dies, see SetAutoReconnect.
+	AutoReconnect bool
+}
+
+func (o UiDriverOptions) withDefaults() UiDriverOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = defaultRPCTimeout
+	}
+	if o.CreateTimeout <= 0 {
+		o.CreateTimeout = defaultCreateTimeout
+	}
+	if o.AgentVersion == "" {
+		o.AgentVersion = defaultAgentVersion
+	}
+	if o.DeviceAgentPath == "" {
+		o.DeviceAgentPath = defaultDeviceAgentPath
+	}
+	if o.RemotePort == 0 {
+		o.RemotePort = defaultUitestPort
+	}
+	return o
+}
+
+// CreateUiDriverWithOptions returns a driver configured by opts.
+func (t *Target) CreateUiDriverWithOptions(opts UiDriverOptions) *UiDriver {
+	return &UiDriver{target: t, opts: opts.withDefaults()}
+}
+
+// Options returns the effective options of the driver.
+func (d *UiDriver) Options() UiDriverOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts
+}
+
+// rpcTimeout returns the timeout for one RPC; zero means the context deadline governs.
+func (d *UiDriver) rpcTimeout(ctx context.Context) time.Duration {
+	if _, ok := ctx.Deadline(); ok {
+		return 0
+	}
+	return d.timeout()
+}
+
+// timeout returns the configured default RPC timeout.
+func (d *UiDriver) timeout() time.Duration {
+	if d.opts.Timeout <= 0 {
+		return defaultRPCTimeout
+	}
+	return d.opts.Timeout
+}
+
+// callBudget returns how long a call issued now may take.
+func (d *UiDriver) callBudget(ctx context.Context) time.Duration {
+	if dl, ok := ctx.Deadline(); ok {
+		return time.Until(dl)
+	}
+	return d.timeout()
+}
@@ -0,0 +1,100 @@
+package hdc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// reconnectTimeout bounds a background recovery after the connection died.
+const reconnectTimeout = 60 * time.Second
+
+// session returns the current RPC connection and driver object name.
+func (d *UiDriver) session() (*uiRPCConn, string) {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
+	return d.conn, d.driverName
+}
+
+// setSession replaces the connection; d.mu must be held.
+func (d *UiDriver) setSession(conn *uiRPCConn, driverName string) {
+	d.connMu.Lock()
+	d.conn = conn
+	d.driverName = driverName
+	d.connMu.Unlock()
+}
+
+func (d *UiDriver) reconnectEnabled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts.AutoReconnect && !d.stopped
+}
+
+// onConnLost runs on the read goroutine of a connection that just died.
+func (d *UiDriver) onConnLost(conn *uiRPCConn, err error) {
+	if cur, _ := d.session(); cur != conn {
+		// already replaced or stopped
+		return
+	}
+	if d.target.client.opts.Debug {
+		fmt.Printf("[ui] rpc connection lost target=%s: %v\n", d.target.key, err)
+	}
+	// pending calls have already failed; the dead conn stays in place so the next ensure restarts
+	if d.reconnectEnabled() {
+		go d.reconnect()
+		return
+	}
+	d.closeCaptureSessions()
+}
+
+// reconnect restarts the driver in the background and resumes capture sessions.
+func (d *UiDriver) reconnect() {
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	err := d.start(ctx)
+	d.mu.Unlock()
+	if err == nil {
+		err = d.resumeCaptures(ctx)
+	}
+	if err != nil {
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] reconnect target=%s failed: %v\n", d.target.key, err)
+		}
+		d.closeCaptureSessions()
+		return
+	}
+	if d.target.client.opts.Debug {
+		fmt.Printf("[ui] reconnected target=%s\n", d.target.key)
+	}
+}
+
+// resumeCaptures restarts the device stream for sessions that survived a reconnect.
+func (d *UiDriver) resumeCaptures(ctx context.Context) error {
+	d.capOpMu.Lock()
+	defer d.capOpMu.Unlock()
+	d.capMu.Lock()
+	if len(d.capSessions) == 0 {
+		d.capStream = 0
+		d.capMu.Unlock()
+		return nil
+	}
+	scale, display := d.capScale, d.capDisplay
+	d.capMu.Unlock()
+	sid, conn, err := d.startDeviceCapture(ctx, scale, display)
+	if err != nil {
+		return err
+	}
+	d.capMu.Lock()
+	d.capStream = uint32(sid)
+	for s := range d.capSessions {
+		s.setID(sid)
+	}
+	d.capMu.Unlock()
+	conn.Subscribe(uint32(sid), d.dispatchFrame)
+	return nil
+}
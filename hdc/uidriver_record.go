@@ -0,0 +1,133 @@
+package hdc
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RecordOptions controls StartRecording.
+type RecordOptions struct {
+	// FPS is the timeline resolution of the output video (default 30).
+	FPS int
+	// Scale is passed to StartCaptureScreen; 0 captures at full size.
+	Scale float64
+}
+
+// ScreenRecorder writes captured frames into an MJPEG AVI file.
+type ScreenRecorder struct {
+	f       *os.File
+	avi     *AVIWriter
+	session *CaptureSession
+	frames  chan recordedFrame
+	done    chan struct{}
+	first   time.Time
+	mu      sync.Mutex
+	stopped bool
+	once    sync.Once
+	stopErr error
+	err     error
+	written atomic.Int64
+	dropped atomic.Int64
+}
+
+type recordedFrame struct {
+	data []byte
+	at   time.Time
+}
+
+// StartRecording starts a screen capture and records it into path until Stop is called.
+func (d *UiDriver) StartRecording(ctx context.Context, path string, opts RecordOptions) (*ScreenRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &ScreenRecorder{
+		f:      f,
+		avi:    NewAVIWriter(f, opts.FPS),
+		frames: make(chan recordedFrame, 64),
+		done:   make(chan struct{}),
+	}
+	go r.loop()
+	s, err := d.StartCaptureScreen(ctx, r.onFrame, opts.Scale)
+	if err != nil {
+		r.closeQueue()
+		<-r.done
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	r.session = s
+	return r, nil
+}
+
+// onFrame runs on the capture session's callback goroutine. b is already a
+// copy of the RPC read buffer that no one modifies, so it is kept as is.
+func (r *ScreenRecorder) onFrame(b []byte) {
+	fr := recordedFrame{data: b, at: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	select {
+	case r.frames <- fr:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+func (r *ScreenRecorder) closeQueue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.frames)
+	}
+}
+
+func (r *ScreenRecorder) loop() {
+	defer close(r.done)
+	for fr := range r.frames {
+		if r.err != nil {
+			continue
+		}
+		if r.first.IsZero() {
+			r.first = fr.at
+		}
+		if err := r.avi.WriteFrame(fr.data, fr.at.Sub(r.first)); err != nil {
+			r.err = err
+			continue
+		}
+		r.written.Add(1)
+	}
+}
+
+// Frames returns the number of captured frames written so far.
+func (r *ScreenRecorder) Frames() int64 { return r.written.Load() }
+
+// Dropped returns how many frames were discarded because the writer fell behind.
+func (r *ScreenRecorder) Dropped() int64 { return r.dropped.Load() }
+
+// Stop stops the capture and finalizes the file. It is safe to call more than once.
+func (r *ScreenRecorder) Stop(ctx context.Context) error {
+	r.once.Do(func() {
+		stopErr := r.session.Stop(ctx)
+		r.closeQueue()
+		<-r.done
+		err := r.err
+		if err == nil {
+			err = r.avi.Close()
+		}
+		if cerr := r.f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = stopErr
+		}
+		r.stopErr = err
+	})
+	return r.stopErr
+}
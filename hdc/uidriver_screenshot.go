@@ -0,0 +1,156 @@
+package hdc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+)
+
+// ScreenshotOptions controls Screenshot output.
+type ScreenshotOptions struct {
+	// Format is "png" (default) or "jpeg".
+	Format string
+	// Quality is the JPEG quality, default 90.
+	Quality int
+	// Region crops the screenshot; the zero rectangle keeps the full screen.
+	Region image.Rectangle
+}
+
+// Screenshot takes a single screenshot and returns it encoded as PNG or JPEG.
+func (d *UiDriver) Screenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	if _, err := screenshotFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	raw, err := d.screenCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertScreenshot(raw, opts)
+}
+
+// ConvertScreenshot crops and re-encodes an encoded screenshot as opts asks;
+// raw is returned as is when nothing needs to change.
+func ConvertScreenshot(raw []byte, opts ScreenshotOptions) ([]byte, error) {
+	format, err := screenshotFormat(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	// no re-encoding needed
+	if opts.Region.Empty() && imageFormat(raw) == format {
+		return raw, nil
+	}
+	img, err := decodeAndCrop(raw, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if format == "jpeg" {
+		q := opts.Quality
+		if q <= 0 {
+			q = 90
+		}
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: q})
+	} else {
+		err = png.Encode(&out, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func screenshotFormat(f string) (string, error) {
+	switch strings.ToLower(f) {
+	case "", "png":
+		return "png", nil
+	case "jpg", "jpeg":
+		return "jpeg", nil
+	}
+	return "", fmt.Errorf("unsupported screenshot format %q", f)
+}
+
+// ScreenshotImage takes a single screenshot and returns the decoded image,
+// cropped to region unless region is empty.
+func (d *UiDriver) ScreenshotImage(ctx context.Context, region image.Rectangle) (image.Image, error) {
+	raw, err := d.screenCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAndCrop(raw, region)
+}
+
+// screenCap saves a screenshot on the device via hypium Driver.screenCap,
+// falling back to snapshot_display, and pulls it back.
+func (d *UiDriver) screenCap(ctx context.Context) ([]byte, error) {
+	if err := d.ensure(ctx); err != nil {
+		return nil, err
+	}
+	stamp := time.Now().UnixNano()
+	remote := fmt.Sprintf("/data/local/tmp/hdckit_screen_%d.png", stamp)
+	// Driver.screenCap only shoots the default display; snapshot_display takes -i
+	display := d.Display()
+	shot := false
+	if display == 0 {
+		res, err := d.callHypium(ctx, "Driver.screenCap", remote)
+		shot, _ = res.(bool)
+		shot = shot && err == nil
+		if !shot && d.target.client.opts.Debug {
+			fmt.Printf("[ui] screenCap failed (res=%v err=%v), fallback to snapshot_display\n", res, err)
+		}
+	}
+	if !shot {
+		// snapshot_display only writes jpeg
+		remote = fmt.Sprintf("/data/local/tmp/hdckit_screen_%d.jpeg", stamp)
+		cmd := "snapshot_display -f " + remote
+		if display != 0 {
+			cmd += fmt.Sprintf(" -i %d", display)
+		}
+		c, err := d.target.Shell(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+		out, _ := c.ReadAll(ctx)
+		if !strings.Contains(strings.ToLower(string(out)), "success") {
+			return nil, errors.New("snapshot_display failed: " + strings.TrimSpace(string(out)))
+		}
+	}
+	defer d.shell(context.Background(), "rm -f "+remote)
+	f, err := os.CreateTemp("", "hdckit_screen_*")
+	if err != nil {
+		return nil, err
+	}
+	local := f.Name()
+	f.Close()
+	defer os.Remove(local)
+	if err := d.target.RecvFile(ctx, remote, local); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(local)
+}
+
+func decodeAndCrop(raw []byte, region image.Rectangle) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	if region.Empty() {
+		return img, nil
+	}
+	r := region.Intersect(img.Bounds())
+	if r.Empty() {
+		return nil, fmt.Errorf("region %v outside screen %v", region, img.Bounds())
+	}
+	if si, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r), nil
+	}
+	return nil, errors.New("image type does not support cropping")
+}
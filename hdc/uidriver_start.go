@@ -0,0 +1,157 @@
+package hdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// daemonReadyBudget bounds how long Start waits for the uitest process to appear.
+const daemonReadyBudget = 5 * time.Second
+
+// StartTimings records how long each phase of the last Start took.
+type StartTimings struct {
+	TestMode       time.Duration
+	SDK            time.Duration
+	Daemon         time.Duration
+	Forward        time.Duration
+	Create         time.Duration
+	Total          time.Duration
+	CreateAttempts int
+}
+
+func (t StartTimings) String() string {
+	r := func(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+	return fmt.Sprintf("testmode=%s sdk=%s daemon=%s forward=%s create=%s (%d attempts) total=%s",
+		r(t.TestMode), r(t.SDK), r(t.Daemon), r(t.Forward), r(t.Create), t.CreateAttempts, r(t.Total))
+}
+
+// StartTimings returns per-phase timing of the last Start.
+func (d *UiDriver) StartTimings() StartTimings {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.timings
+}
+
+// daemonPids returns the pids of the uitest daemon. It matches the daemon's
+// command line, so other uitest processes such as `uitest uiRecord record`
+// are neither mistaken for the daemon nor killed with it.
+func (d *UiDriver) daemonPids(ctx context.Context) []string {
+	out, err := d.target.shellOutput(ctx, "ps -ef")
+	if err != nil {
+		return nil
+	}
+	return parseDaemonPids(out)
+}
+
+// parseDaemonPids picks the "uitest start-daemon" lines of `ps -ef` output.
+func parseDaemonPids(out string) []string {
+	var pids []string
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		// UID PID PPID C STIME TTY TIME CMD...
+		if len(f) < 9 {
+			continue
+		}
+		if (f[7] == "uitest" || strings.HasSuffix(f[7], "/uitest")) && f[8] == "start-daemon" {
+			pids = append(pids, f[1])
+		}
+	}
+	return pids
+}
+
+// daemonRunning reports whether the uitest daemon is alive on the device.
+func (d *UiDriver) daemonRunning(ctx context.Context) bool {
+	return len(d.daemonPids(ctx)) > 0
+}
+
+// killDaemon kills the uitest daemon, if running.
+func (d *UiDriver) killDaemon(ctx context.Context) {
+	if pids := d.daemonPids(ctx); len(pids) > 0 {
+		_ = d.shell(ctx, "kill -9 "+strings.Join(pids, " "))
+	}
+}
+
+// ensureDaemon starts the uitest daemon unless it is already alive and waits for
+// its process to appear. restart kills a running daemon first.
+func (d *UiDriver) ensureDaemon(ctx context.Context, restart bool) error {
+	if restart {
+		d.killDaemon(ctx)
+	} else if d.daemonRunning(ctx) {
+		if d.target.client.opts.Debug {
+			fmt.Println("[ui] uitest daemon already running")
+		}
+		return nil
+	}
+	if err := d.shell(ctx, "uitest start-daemon singleness"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(daemonReadyBudget)
+	bo := newBackoff(50*time.Millisecond, 500*time.Millisecond)
+	for !d.daemonRunning(ctx) {
+		if time.Now().After(deadline) {
+			return errors.New("uitest daemon did not start")
+		}
+		if err := bo.wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectAndCreate retries rpc.Connect + Driver.create with backoff until it
+// succeeds or budget runs out. It returns the number of attempts made.
+func (d *UiDriver) connectAndCreate(ctx context.Context, port int, budget time.Duration) (*uiRPCConn, string, int, error) {
+	deadline := time.Now().Add(budget)
+	bo := newBackoff(100*time.Millisecond, time.Second)
+	for attempt := 1; ; attempt++ {
+		rpc := &uiRPCConn{}
+		err := rpc.Connect(ctx, port)
+		if err == nil {
+			// bound each attempt by the RPC timeout even under a long ctx deadline, so retries happen
+			var resp Response
+			resp, err = rpc.SendMessage(ctx, hypiumPayload("Driver.create", nil, []any{}), d.timeout())
+			if err == nil {
+				if s, ok := resp.Result.(string); ok {
+					return rpc, s, attempt, nil
+				}
+				err = errors.New("invalid create response")
+			}
+			rpc.Close()
+		}
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] create attempt %d failed: %v\n", attempt, err)
+		}
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return nil, "", attempt, err
+		}
+		if werr := bo.wait(ctx); werr != nil {
+			return nil, "", attempt, err
+		}
+	}
+}
+
+// backoff sleeps with exponentially growing delays.
+type backoff struct {
+	next time.Duration
+	max  time.Duration
+}
+
+func newBackoff(initial, max time.Duration) *backoff { return &backoff{next: initial, max: max} }
+
+func (b *backoff) wait(ctx context.Context) error {
+	t := time.NewTimer(b.next)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	b.next *= 2
+	if b.next > b.max {
+		b.next = b.max
+	}
+	return nil
+}
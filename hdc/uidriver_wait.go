@@ -0,0 +1,186 @@
+package hdc
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Condition is a predicate polled by WaitUntil.
+type Condition interface {
+	// String describes the condition for error messages.
+	String() string
+	// Check reports whether the condition holds, plus the value it observed.
+	Check(ctx context.Context, d *UiDriver) (ok bool, observed any, err error)
+}
+
+type funcCondition struct {
+	desc string
+	fn   func(ctx context.Context, d *UiDriver) (bool, any, error)
+}
+
+func (c funcCondition) String() string { return c.desc }
+func (c funcCondition) Check(ctx context.Context, d *UiDriver) (bool, any, error) {
+	return c.fn(ctx, d)
+}
+
+// NewCondition builds a Condition from a description and a check function.
+func NewCondition(desc string, fn func(ctx context.Context, d *UiDriver) (bool, any, error)) Condition {
+	return funcCondition{desc: desc, fn: fn}
+}
+
+// WaitError reports a condition that did not hold before the context ended.
+type WaitError struct {
+	Condition string
+	// Observed is the value seen by the last check.
+	Observed any
+	// LastErr is the error returned by the last check, if any.
+	LastErr  error
+	Checks   int
+	Elapsed  time.Duration
+	ctxError error
+}
+
+func (e *WaitError) Error() string {
+	msg := fmt.Sprintf("wait for %s: %v after %s (%d checks), last observed: %v",
+		e.Condition, e.ctxError, e.Elapsed.Round(time.Millisecond), e.Checks, e.Observed)
+	if e.LastErr != nil {
+		msg += ", last error: " + e.LastErr.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the context error, so errors.Is(err, context.DeadlineExceeded) works.
+func (e *WaitError) Unwrap() error { return e.ctxError }
+
+// WaitUntil polls cond every interval until it holds or ctx ends.
+// Check errors are treated as "not yet" and reported in the WaitError.
+func (d *UiDriver) WaitUntil(ctx context.Context, cond Condition, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	start := time.Now()
+	werr := &WaitError{Condition: cond.String()}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ok, observed, err := cond.Check(ctx, d)
+		if ok && err == nil {
+			return nil
+		}
+		// a check cut short by the deadline says nothing new; keep the previous observation
+		if err == nil || ctx.Err() == nil {
+			werr.Checks++
+			werr.Observed, werr.LastErr = observed, err
+		}
+		select {
+		case <-ctx.Done():
+			werr.ctxError = ctx.Err()
+			werr.Elapsed = time.Since(start)
+			return werr
+		case <-ticker.C:
+		}
+	}
+}
+
+// ComponentExists holds when a node matches sel.
+func ComponentExists(sel Selector) Condition {
+	return NewCondition("component "+sel.String()+" exists", func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		root, err := d.DumpLayout(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		if n := root.Find(sel); n != nil {
+			return true, n.Attr("bounds"), nil
+		}
+		return false, "not found", nil
+	})
+}
+
+// ComponentGone holds when no node matches sel.
+func ComponentGone(sel Selector) Condition {
+	return NewCondition("component "+sel.String()+" gone", func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		root, err := d.DumpLayout(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		if n := root.Find(sel); n != nil {
+			return false, "present at " + n.Attr("bounds"), nil
+		}
+		return true, "not found", nil
+	})
+}
+
+// TextEquals holds when the node matching sel has exactly text.
+func TextEquals(sel Selector, text string) Condition {
+	return NewCondition(fmt.Sprintf("component %s text == %q", sel, text), func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		root, err := d.DumpLayout(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		n := root.Find(sel)
+		if n == nil {
+			return false, "component not found", nil
+		}
+		return n.Text() == text, n.Text(), nil
+	})
+}
+
+// ForegroundAbilityIs holds when bundle (and ability, if not empty) is in the foreground.
+func ForegroundAbilityIs(bundle, ability string) Condition {
+	want := AbilityInfo{Bundle: bundle, Ability: ability}
+	return NewCondition("foreground ability "+want.String(), func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		cur, err := d.target.ForegroundAbility(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		ok := cur.Bundle == bundle && (ability == "" || cur.Ability == ability)
+		return ok, cur.String(), nil
+	})
+}
+
+// ScreenIdle holds when the UI has had no changes for idle, using hypium Driver.waitForIdle.
+func ScreenIdle(idle time.Duration) Condition {
+	return NewCondition(fmt.Sprintf("screen idle for %s", idle), func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		if err := d.ensure(ctx); err != nil {
+			return false, nil, err
+		}
+		// keep the device-side wait inside the call budget; WaitUntil polls again
+		wait := d.callBudget(ctx) - 500*time.Millisecond
+		if wait < 100*time.Millisecond {
+			wait = 100 * time.Millisecond
+		}
+		res, err := d.callHypium(ctx, "Driver.waitForIdle", int(idle.Milliseconds()), int(wait.Milliseconds()))
+		if err != nil {
+			return false, nil, err
+		}
+		ok, _ := res.(bool)
+		return ok, res, nil
+	})
+}
+
+// LayoutStable holds when the layout tree has not changed for at least stable.
+// Each call returns a fresh condition with its own state.
+func LayoutStable(stable time.Duration) Condition {
+	var last [sha1.Size]byte
+	var since time.Time
+	return NewCondition(fmt.Sprintf("layout stable for %s", stable), func(ctx context.Context, d *UiDriver) (bool, any, error) {
+		v, err := d.CaptureLayout(ctx)
+		if err != nil {
+			return false, nil, err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return false, nil, err
+		}
+		sum := sha1.Sum(b)
+		now := time.Now()
+		if since.IsZero() || sum != last {
+			last, since = sum, now
+		}
+		held := now.Sub(since)
+		return held >= stable, fmt.Sprintf("unchanged for %s", held.Round(time.Millisecond)), nil
+	})
+}
@@ -0,0 +1,308 @@
+package hdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatcherAction is what a Watcher does when its selector matches.
+type WatcherAction int
+
+const (
+	// WatchClick taps the matched node, or the node matched by Watcher.Click.
+	WatchClick WatcherAction = iota
+	// WatchBack presses the back key.
+	WatchBack
+	// WatchCallback calls Watcher.Callback.
+	WatchCallback
+)
+
+// Watcher dismisses an unexpected popup, e.g. a permission prompt or an update nag.
+type Watcher struct {
+	// Name identifies the watcher for RemoveWatcher; it defaults to Match.String().
+	Name string
+	// Match selects the node whose presence triggers the watcher.
+	Match  Selector
+	Action WatcherAction
+	// Click, if set, selects the node tapped by WatchClick instead of the matched one,
+	// e.g. Match the dialog title and Click its "Allow" button.
+	Click *Selector
+	// Callback runs for WatchCallback with the matched node.
+	Callback func(ctx context.Context, d *UiDriver, n *LayoutNode) error
+}
+
+func (w Watcher) name() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return w.Match.String()
+}
+
+// AddWatcher registers w, replacing a watcher with the same name. Watchers run
+// when FindComponent misses (then it looks again), on RunWatchers, and in the
+// background after StartWatching.
+func (d *UiDriver) AddWatcher(w Watcher) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	for i := range d.watchers {
+		if d.watchers[i].name() == w.name() {
+			d.watchers[i] = w
+			return
+		}
+	}
+	d.watchers = append(d.watchers, w)
+}
+
+// RemoveWatcher unregisters the watcher called name.
+func (d *UiDriver) RemoveWatcher(name string) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	for i := range d.watchers {
+		if d.watchers[i].name() == name {
+			d.watchers = append(d.watchers[:i], d.watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// RunWatchers checks every watcher against the current layout and returns the
+// names of those that fired.
+func (d *UiDriver) RunWatchers(ctx context.Context) ([]string, error) {
+	d.watchMu.Lock()
+	ws := append([]Watcher(nil), d.watchers...)
+	d.watchMu.Unlock()
+	if len(ws) == 0 {
+		return nil, nil
+	}
+	// one run at a time; the background loop and FindComponent may overlap
+	d.watchRun.Lock()
+	defer d.watchRun.Unlock()
+	root, err := d.DumpLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var fired []string
+	for _, w := range ws {
+		n := root.Find(w.Match)
+		if n == nil {
+			continue
+		}
+		if d.target.client.opts.Debug {
+			fmt.Printf("[ui] watcher %s fired\n", w.name())
+		}
+		if err := d.runWatcher(ctx, w, root, n); err != nil {
+			return fired, fmt.Errorf("watcher %s: %w", w.name(), err)
+		}
+		fired = append(fired, w.name())
+	}
+	return fired, nil
+}
+
+func (d *UiDriver) runWatcher(ctx context.Context, w Watcher, root, n *LayoutNode) error {
+	switch w.Action {
+	case WatchClick:
+		if w.Click != nil {
+			if n = root.Find(*w.Click); n == nil {
+				return fmt.Errorf("%w: %s", ErrComponentNotFound, w.Click)
+			}
+		}
+		c := n.Center()
+		return d.Click(ctx, c.X, c.Y)
+	case WatchBack:
+		return d.PressBack(ctx)
+	case WatchCallback:
+		if w.Callback == nil {
+			return errors.New("no callback")
+		}
+		return w.Callback(ctx, d, n)
+	}
+	return fmt.Errorf("unknown action %d", w.Action)
+}
+
+// StartWatching runs the watchers every interval (default 1s) until ctx ends
+// or the returned stop function is called.
+func (d *UiDriver) StartWatching(ctx context.Context, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := d.RunWatchers(ctx); err != nil && d.target.client.opts.Debug {
+				fmt.Printf("[ui] watchers: %v\n", err)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// UIEvent is a UI event reported by the uitest event observer.
+type UIEvent struct {
+	Type   string    `json:"type"`
+	Bundle string    `json:"bundleName"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"-"`
+}
+
+// ObserveUIEvents calls fn for every eventType event ("toastShow", "dialogShow")
+// until ctx ends. It uses Driver.createUIEventObserver and re-arms
+// UIEventObserver.once after each event, so events in that gap can be missed.
+func (d *UiDriver) ObserveUIEvents(ctx context.Context, eventType string, fn func(UIEvent)) error {
+	var obs *RemoteObject
+	defer func() {
+		if obs != nil {
+			_ = obs.Release(context.Background())
+		}
+	}()
+	bo := newBackoff(100*time.Millisecond, 2*time.Second)
+	for ctx.Err() == nil {
+		if obs == nil || obs.usable() != nil {
+			o, err := Invoke[*RemoteObject](ctx, d, "Driver.createUIEventObserver", d.Driver())
+			if err != nil || o == nil {
+				if d.target.client.opts.Debug {
+					fmt.Printf("[ui] createUIEventObserver failed: %v\n", err)
+				}
+				if werr := bo.wait(ctx); werr != nil {
+					return werr
+				}
+				continue
+			}
+			obs = o
+		}
+		// the reply arrives when the event fires, so wait without an RPC timeout
+		ref := obs.ref
+		res, _, err := d.sendTimeout(ctx, 0, func(string) any {
+			return hypiumPayload("UIEventObserver.once", ref, []any{eventType})
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if d.target.client.opts.Debug {
+				fmt.Printf("[ui] UIEventObserver.once failed: %v\n", err)
+			}
+			if werr := bo.wait(ctx); werr != nil {
+				return werr
+			}
+			continue
+		}
+		ev := UIEvent{Type: eventType, Time: time.Now()}
+		if b, err := json.Marshal(res); err == nil {
+			_ = json.Unmarshal(b, &ev)
+		}
+		if ev.Type == "" {
+			ev.Type = eventType
+		}
+		fn(ev)
+	}
+	return ctx.Err()
+}
+
+// ToastListener records toast texts shown while it runs.
+type ToastListener struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	toasts []UIEvent
+	gen    int // bumped by Clear
+	notify chan struct{}
+}
+
+// StartToastListener starts recording toasts in the background.
+func (d *UiDriver) StartToastListener(ctx context.Context) (*ToastListener, error) {
+	if err := d.ensure(ctx); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &ToastListener{cancel: cancel, done: make(chan struct{}), notify: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		_ = d.ObserveUIEvents(ctx, "toastShow", l.add)
+	}()
+	return l, nil
+}
+
+func (l *ToastListener) add(ev UIEvent) {
+	l.mu.Lock()
+	l.toasts = append(l.toasts, ev)
+	close(l.notify)
+	l.notify = make(chan struct{})
+	l.mu.Unlock()
+}
+
+// Toasts returns the toasts seen so far, oldest first.
+func (l *ToastListener) Toasts() []UIEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]UIEvent(nil), l.toasts...)
+}
+
+// Last returns the most recent toast.
+func (l *ToastListener) Last() (UIEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.toasts) == 0 {
+		return UIEvent{}, false
+	}
+	return l.toasts[len(l.toasts)-1], true
+}
+
+// WaitFor returns the first toast, recorded or upcoming, whose text contains substr.
+func (l *ToastListener) WaitFor(ctx context.Context, substr string) (UIEvent, error) {
+	seen, gen := 0, -1
+	for {
+		l.mu.Lock()
+		if gen != l.gen {
+			// cleared since the last look: everything recorded is new
+			seen, gen = 0, l.gen
+		}
+		for _, ev := range l.toasts[seen:] {
+			if strings.Contains(ev.Text, substr) {
+				l.mu.Unlock()
+				return ev, nil
+			}
+		}
+		seen = len(l.toasts)
+		notify := l.notify
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return UIEvent{}, fmt.Errorf("wait for toast %q: %w", substr, ctx.Err())
+		case <-l.done:
+			return UIEvent{}, errors.New("toast listener stopped")
+		case <-notify:
+		}
+	}
+}
+
+// Clear forgets the recorded toasts.
+func (l *ToastListener) Clear() {
+	l.mu.Lock()
+	l.toasts = nil
+	l.gen++
+	l.mu.Unlock()
+}
+
+// Stop ends the listener and waits for it to exit.
+func (l *ToastListener) Stop() {
+	l.cancel()
+	<-l.done
+}
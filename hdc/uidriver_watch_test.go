@@ -0,0 +1,36 @@
+package hdc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestToastListenerWaitForAcrossClear(t *testing.T) {
+	l := &ToastListener{done: make(chan struct{}), notify: make(chan struct{})}
+	l.add(UIEvent{Text: "one"})
+	l.add(UIEvent{Text: "two"})
+	got := make(chan UIEvent, 1)
+	errc := make(chan error, 1)
+	go func() {
+		ev, err := l.WaitFor(context.Background(), "saved")
+		if err != nil {
+			errc <- err
+			return
+		}
+		got <- ev
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.Clear()
+	l.add(UIEvent{Text: "saved"})
+	select {
+	case ev := <-got:
+		if ev.Text != "saved" {
+			t.Fatalf("got %q", ev.Text)
+		}
+	case err := <-errc:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitFor missed the toast after Clear")
+	}
+}
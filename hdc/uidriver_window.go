@@ -0,0 +1,214 @@
+package hdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrWindowNotFound is returned when no window matches a WindowFilter.
+var ErrWindowNotFound = errors.New("window not found")
+
+// WindowMode is the uitest window mode.
+type WindowMode int
+
+const (
+	WindowFullscreen WindowMode = iota
+	WindowPrimary
+	WindowSecondary
+	WindowFloating
+)
+
+func (m WindowMode) String() string {
+	switch m {
+	case WindowFullscreen:
+		return "fullscreen"
+	case WindowPrimary:
+		return "primary"
+	case WindowSecondary:
+		return "secondary"
+	case WindowFloating:
+		return "floating"
+	}
+	return fmt.Sprintf("WindowMode(%d)", int(m))
+}
+
+// ResizeDirection is the edge or corner dragged by UiWindow.Resize.
+type ResizeDirection int
+
+const (
+	ResizeLeft ResizeDirection = iota
+	ResizeRight
+	ResizeUp
+	ResizeDown
+	ResizeLeftUp
+	ResizeLeftDown
+	ResizeRightUp
+	ResizeRightDown
+)
+
+// WindowFilter selects a window for FindWindow; empty fields are ignored.
+type WindowFilter struct {
+	Bundle  string `json:"bundleName,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Focused bool   `json:"focused,omitempty"`
+	Active  bool   `json:"active,omitempty"`
+	// DisplayID restricts the search to one display; nil means any.
+	DisplayID *int `json:"displayId,omitempty"`
+}
+
+// DisplaySize is the size of a display in pixels.
+type DisplaySize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (s DisplaySize) String() string { return fmt.Sprintf("%dx%d", s.Width, s.Height) }
+
+// hypiumRect is the uitest Rect shape.
+type hypiumRect struct {
+	Left      int `json:"left"`
+	Top       int `json:"top"`
+	Right     int `json:"right"`
+	Bottom    int `json:"bottom"`
+	DisplayID int `json:"displayId"`
+}
+
+func (r hypiumRect) rectangle() image.Rectangle { return image.Rect(r.Left, r.Top, r.Right, r.Bottom) }
+
+// SetDisplay selects the display used by capture, screenshots, display size and
+// input. 0 is the default display. Capture streams already running keep their display.
+func (d *UiDriver) SetDisplay(id int) {
+	d.mu.Lock()
+	d.display = id
+	d.mu.Unlock()
+}
+
+// Display returns the display selected with SetDisplay.
+func (d *UiDriver) Display() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.display
+}
+
+// GetDisplaySize returns the size of the selected display.
+func (d *UiDriver) GetDisplaySize(ctx context.Context) (DisplaySize, error) {
+	if err := d.ensure(ctx); err != nil {
+		return DisplaySize{}, err
+	}
+	var res any
+	var err error
+	if id := d.Display(); id != 0 {
+		res, err = d.callHypium(ctx, "Driver.getDisplaySize", id)
+	} else {
+		res, err = d.call(ctx, "CtrlCmd", "getDisplaySize", nil)
+	}
+	if err != nil {
+		return DisplaySize{}, err
+	}
+	return parseDisplaySize(res)
+}
+
+// parseDisplaySize accepts {width,height} and the hypium Point {x,y}.
+func parseDisplaySize(v any) (DisplaySize, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return DisplaySize{}, fmt.Errorf("unexpected display size result %v", v)
+	}
+	w, okW := toInt(m["width"])
+	h, okH := toInt(m["height"])
+	if !okW || !okH {
+		w, okW = toInt(m["x"])
+		h, okH = toInt(m["y"])
+	}
+	if !okW || !okH {
+		return DisplaySize{}, fmt.Errorf("unexpected display size result %v", v)
+	}
+	return DisplaySize{Width: w, Height: h}, nil
+}
+
+// UiWindow is a window found by FindWindow. Release it when done.
+type UiWindow struct {
+	obj *RemoteObject
+}
+
+// FindWindow returns the first window matching f.
+func (d *UiDriver) FindWindow(ctx context.Context, f WindowFilter) (*UiWindow, error) {
+	res, err := d.CallHypium(ctx, "Driver.findWindow", d.Driver(), f)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := res.(*RemoteObject)
+	if !ok {
+		b, _ := json.Marshal(f)
+		return nil, fmt.Errorf("%w: %s", ErrWindowNotFound, b)
+	}
+	return &UiWindow{obj: obj}, nil
+}
+
+// Object returns the underlying remote handle for CallHypium.
+func (w *UiWindow) Object() *RemoteObject { return w.obj }
+
+func (w *UiWindow) String() string { return w.obj.Ref() }
+
+func (w *UiWindow) BundleName(ctx context.Context) (string, error) {
+	return Invoke[string](ctx, w.obj.d, "UiWindow.getBundleName", w.obj)
+}
+
+func (w *UiWindow) Title(ctx context.Context) (string, error) {
+	return Invoke[string](ctx, w.obj.d, "UiWindow.getTitle", w.obj)
+}
+
+func (w *UiWindow) Bounds(ctx context.Context) (image.Rectangle, error) {
+	r, err := Invoke[hypiumRect](ctx, w.obj.d, "UiWindow.getBounds", w.obj)
+	return r.rectangle(), err
+}
+
+func (w *UiWindow) Mode(ctx context.Context) (WindowMode, error) {
+	return Invoke[WindowMode](ctx, w.obj.d, "UiWindow.getWindowMode", w.obj)
+}
+
+func (w *UiWindow) DisplayID(ctx context.Context) (int, error) {
+	return Invoke[int](ctx, w.obj.d, "UiWindow.getDisplayId", w.obj)
+}
+
+func (w *UiWindow) IsFocused(ctx context.Context) (bool, error) {
+	return Invoke[bool](ctx, w.obj.d, "UiWindow.isFocused", w.obj)
+}
+
+func (w *UiWindow) IsActive(ctx context.Context) (bool, error) {
+	return Invoke[bool](ctx, w.obj.d, "UiWindow.isActive", w.obj)
+}
+
+func (w *UiWindow) Focus(ctx context.Context) error { return w.do(ctx, "UiWindow.focus") }
+
+func (w *UiWindow) MoveTo(ctx context.Context, x, y int) error {
+	return w.do(ctx, "UiWindow.moveTo", x, y)
+}
+
+func (w *UiWindow) Resize(ctx context.Context, width, height int, dir ResizeDirection) error {
+	return w.do(ctx, "UiWindow.resize", width, height, int(dir))
+}
+
+func (w *UiWindow) Split(ctx context.Context) error    { return w.do(ctx, "UiWindow.split") }
+func (w *UiWindow) Maximize(ctx context.Context) error { return w.do(ctx, "UiWindow.maximize") }
+func (w *UiWindow) Minimize(ctx context.Context) error { return w.do(ctx, "UiWindow.minimize") }
+func (w *UiWindow) Resume(ctx context.Context) error   { return w.do(ctx, "UiWindow.resume") }
+func (w *UiWindow) Close(ctx context.Context) error    { return w.do(ctx, "UiWindow.close") }
+
+// Release frees the window handle in the agent.
+func (w *UiWindow) Release(ctx context.Context) error { return w.obj.Release(ctx) }
+
+// do runs a window operation; uitest reports refused operations as false.
+func (w *UiWindow) do(ctx context.Context, api string, args ...any) error {
+	res, err := w.obj.Call(ctx, api, args...)
+	if err != nil {
+		return err
+	}
+	if ok, isBool := res.(bool); isBool && !ok {
+		return fmt.Errorf("%s on %s refused", api, w.obj.Ref())
+	}
+	return nil
+}
@@ -8,9 +8,9 @@ import (
 var reKeyVal = regexp.MustCompile(`^\s*(.*?) = (.*?)\r?$`)
 
 type Forward struct {
-	Target string
-	Local  string
-	Remote string
+	Target string `json:"target"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
 }
 
 func readTargets(s string) []string {
@@ -65,3 +65,25 @@ func parseParameters(s string) map[string]string {
 	}
 	return res
 }
+
+// imageFormat sniffs a frame payload and returns "png", "jpeg" or "".
+func imageFormat(b []byte) string {
+	if len(b) >= 8 && b[0] == 0x89 && b[1] == 0x50 && b[2] == 0x4E && b[3] == 0x47 {
+		return "png"
+	}
+	if len(b) >= 3 && b[0] == 0xFF && b[1] == 0xD8 && b[2] == 0xFF {
+		return "jpeg"
+	}
+	return ""
+}
+
+func imageContentType(b []byte) string {
+	switch imageFormat(b) {
+	case "png":
+		return "image/png"
+	case "jpeg":
+		return "image/jpeg"
+	default:
+		return "application/octet-stream"
+	}
+}
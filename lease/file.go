@@ -0,0 +1,186 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileStore keeps one lease file per device in a directory and serializes
+// updates with an OS file lock (flock, LockFileEx) on a lock file, so separate
+// processes on one machine (parallel `go test` packages, CI jobs) never share
+// a device. The OS drops the lock of a crashed process.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore returns a store in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// DefaultFileStore returns the store shared by every user of the machine's temp dir.
+func DefaultFileStore() (*FileStore, error) {
+	return NewFileStore(filepath.Join(os.TempDir(), "hdckit-leases"))
+}
+
+func (s *FileStore) TryAcquire(ctx context.Context, serial, owner string, ttl time.Duration) (bool, error) {
+	ok := false
+	err := s.locked(ctx, func() error {
+		now := time.Now()
+		r, err := s.read(serial)
+		if err != nil {
+			return err
+		}
+		if r.live(now) {
+			return nil
+		}
+		ok = true
+		return s.write(serial, Record{Owner: owner, Expires: now.Add(ttl)})
+	})
+	return ok, err
+}
+
+func (s *FileStore) Renew(ctx context.Context, serial, owner string, ttl time.Duration) error {
+	return s.locked(ctx, func() error {
+		now := time.Now()
+		r, err := s.read(serial)
+		if err != nil {
+			return err
+		}
+		if r.Owner != owner || !r.live(now) {
+			return ErrLeaseLost
+		}
+		return s.write(serial, Record{Owner: owner, Expires: now.Add(ttl)})
+	})
+}
+
+func (s *FileStore) Release(ctx context.Context, serial, owner string) error {
+	return s.locked(ctx, func() error {
+		r, err := s.read(serial)
+		if err != nil {
+			return err
+		}
+		if r.Owner != owner {
+			return ErrLeaseLost
+		}
+		return os.Remove(s.path(serial))
+	})
+}
+
+// Leases returns the unexpired leases by serial.
+func (s *FileStore) Leases() (map[string]Record, error) {
+	ents, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := map[string]Record{}
+	for _, e := range ents {
+		if !strings.HasSuffix(e.Name(), ".lease") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.Dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var r struct {
+			Record
+			Serial string `json:"serial"`
+		}
+		if json.Unmarshal(b, &r) == nil && r.live(now) {
+			out[r.Serial] = r.Record
+		}
+	}
+	return out, nil
+}
+
+func (s *FileStore) path(serial string) string {
+	return filepath.Join(s.Dir, leaseFileName(serial)+".lease")
+}
+
+// leaseFileName escapes every byte outside [a-z0-9.-] as _XX, so distinct
+// serials such as 1.2.3.4:5555 and 1.2.3.4_5555 get distinct files, also on
+// case-insensitive file systems.
+func leaseFileName(serial string) string {
+	var b strings.Builder
+	for i := 0; i < len(serial); i++ {
+		c := serial[i]
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '.', c == '-':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "_%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func (s *FileStore) read(serial string) (Record, error) {
+	b, err := os.ReadFile(s.path(serial))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	var r Record
+	if err := json.Unmarshal(b, &r); err != nil {
+		// a torn write is as good as no lease
+		return Record{}, nil
+	}
+	return r, nil
+}
+
+func (s *FileStore) write(serial string, r Record) error {
+	b, err := json.Marshal(struct {
+		Record
+		Serial string `json:"serial"`
+	}{r, serial})
+	if err != nil {
+		return err
+	}
+	tmp := s.path(serial) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(serial))
+}
+
+// locked runs fn while holding the store lock.
+func (s *FileStore) locked(ctx context.Context, fn func() error) error {
+	f, err := os.OpenFile(filepath.Join(s.Dir, ".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	// closing the file releases the lock
+	defer f.Close()
+	var tick *time.Ticker
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", f.Name(), err)
+		}
+		if ok {
+			break
+		}
+		if tick == nil {
+			tick = time.NewTicker(10 * time.Millisecond)
+			defer tick.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+	return fn()
+}
@@ -0,0 +1,297 @@
+// Package lease hands out exclusive device leases, so parallel jobs sharing a
+// pool of devices never drive the same one.
+//
+//	store, _ := lease.DefaultFileStore()
+//	m := lease.NewManager(client, store, lease.Options{})
+//	l, err := m.Acquire(ctx, lease.Constraints{Model: "ALN-AL00"})
+//	if err != nil { ... }
+//	defer l.Release(context.Background())
+//	t := l.Target()
+package lease
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+// Device parameters read by Constraints.
+const (
+	ParamModel      = "const.product.model"
+	ParamOSFullName = "const.ohos.fullname"
+	ParamSWVersion  = "const.product.software.version"
+	ParamAPIVersion = "const.ohos.apiversion"
+)
+
+// Constraints select the devices a job accepts; empty fields match anything.
+type Constraints struct {
+	// Serials restricts the lease to these devices.
+	Serials []string
+	// Model must equal const.product.model.
+	Model string
+	// OSVersion must appear in const.ohos.fullname or const.product.software.version,
+	// e.g. "5.0.0".
+	OSVersion string
+	// MinAPI is the lowest accepted const.ohos.apiversion.
+	MinAPI int
+	// Params must all equal the device parameters of the same name.
+	Params map[string]string
+}
+
+// Match reports whether a device with the given serial and parameters qualifies.
+func (c Constraints) Match(serial string, params map[string]string) bool {
+	if len(c.Serials) > 0 && !contains(c.Serials, serial) {
+		return false
+	}
+	if c.Model != "" && params[ParamModel] != c.Model {
+		return false
+	}
+	if c.OSVersion != "" && !strings.Contains(params[ParamOSFullName], c.OSVersion) &&
+		!strings.Contains(params[ParamSWVersion], c.OSVersion) {
+		return false
+	}
+	if c.MinAPI > 0 {
+		if api, err := strconv.Atoi(params[ParamAPIVersion]); err != nil || api < c.MinAPI {
+			return false
+		}
+	}
+	for k, v := range c.Params {
+		if params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraints) needParams() bool {
+	return c.Model != "" || c.OSVersion != "" || c.MinAPI > 0 || len(c.Params) > 0
+}
+
+// Options tunes a Manager.
+type Options struct {
+	// Owner names the holder in the store (default host:pid:random).
+	Owner string
+	// TTL is how long a lease survives without a heartbeat (default 30s).
+	TTL time.Duration
+	// Poll is how often Acquire looks again while every match is taken (default 2s).
+	Poll time.Duration
+}
+
+// Manager acquires leases on the devices of a Client.
+type Manager struct {
+	client *hdc.Client
+	store  Store
+	opts   Options
+
+	mu     sync.Mutex
+	params map[string]map[string]string
+}
+
+// NewManager returns a manager leasing the devices of c through store.
+func NewManager(c *hdc.Client, store Store, opts Options) *Manager {
+	if opts.Owner == "" {
+		host, _ := os.Hostname()
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		opts.Owner = fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 30 * time.Second
+	}
+	if opts.Poll <= 0 {
+		opts.Poll = 2 * time.Second
+	}
+	return &Manager{client: c, store: store, opts: opts, params: map[string]map[string]string{}}
+}
+
+// Owner returns the name the manager holds leases under.
+func (m *Manager) Owner() string { return m.opts.Owner }
+
+// Acquire waits until a connected device matching cons is free, leases it and
+// keeps the lease alive until Release. Newly connected devices are picked up
+// as soon as the tracker reports them.
+func (m *Manager) Acquire(ctx context.Context, cons Constraints) (*Lease, error) {
+	if l, err := m.TryAcquire(ctx, cons); l != nil || err != nil {
+		return l, err
+	}
+	tr, err := m.client.TrackTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tr.Close()
+	ticker := time.NewTicker(m.opts.Poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("acquire device: %w", ctx.Err())
+		case <-tr.Added():
+		case <-tr.Removed():
+			continue
+		case <-tr.Errors():
+			continue
+		case <-ticker.C:
+		}
+		if l, err := m.TryAcquire(ctx, cons); l != nil || err != nil {
+			return l, err
+		}
+	}
+}
+
+// TryAcquire leases a free matching device, or returns nil, nil when there is none.
+func (m *Manager) TryAcquire(ctx context.Context, cons Constraints) (*Lease, error) {
+	serials, err := m.client.ListTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range serials {
+		var params map[string]string
+		if cons.needParams() {
+			if params, err = m.deviceParams(ctx, s); err != nil {
+				// offline or unauthorized devices are not candidates
+				continue
+			}
+		}
+		if !cons.Match(s, params) {
+			continue
+		}
+		ok, err := m.store.TryAcquire(ctx, s, m.opts.Owner, m.opts.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return m.start(s, params), nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Manager) deviceParams(ctx context.Context, serial string) (map[string]string, error) {
+	m.mu.Lock()
+	p, ok := m.params[serial]
+	m.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+	p, err := m.client.Target(serial).GetParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.params[serial] = p
+	m.mu.Unlock()
+	return p, nil
+}
+
+// Lease is an exclusive hold on one device.
+type Lease struct {
+	m      *Manager
+	serial string
+	params map[string]string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	lost   chan struct{}
+
+	mu       sync.Mutex
+	err      error
+	released bool
+}
+
+func (m *Manager) start(serial string, params map[string]string) *Lease {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Lease{m: m, serial: serial, params: params, cancel: cancel, done: make(chan struct{}), lost: make(chan struct{})}
+	go l.heartbeat(ctx)
+	return l
+}
+
+// heartbeat renews the lease every third of the TTL; a failed renewal is
+// retried until the lease is reported lost or would have expired.
+func (l *Lease) heartbeat(ctx context.Context) {
+	defer close(l.done)
+	ttl := l.m.opts.TTL
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := l.m.store.Renew(ctx, l.serial, l.m.opts.Owner, ttl)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrLeaseLost) || time.Since(renewed) >= ttl {
+			l.mu.Lock()
+			l.err = fmt.Errorf("lease on %s: %w", l.serial, err)
+			l.mu.Unlock()
+			close(l.lost)
+			return
+		}
+	}
+}
+
+// Serial returns the leased device.
+func (l *Lease) Serial() string { return l.serial }
+
+// Target returns the leased device as a Target.
+func (l *Lease) Target() *hdc.Target { return l.m.client.Target(l.serial) }
+
+// Params returns the device parameters read while matching, nil when the
+// constraints did not need them.
+func (l *Lease) Params() map[string]string { return l.params }
+
+// Lost is closed when the heartbeat could not keep the lease; stop using the device then.
+func (l *Lease) Lost() <-chan struct{} { return l.lost }
+
+// Err reports why the lease was lost.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Release stops the heartbeat and frees the device. Releasing twice is a no-op.
+func (l *Lease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return nil
+	}
+	l.released = true
+	lostErr := l.err
+	l.mu.Unlock()
+	if lostErr != nil {
+		return lostErr
+	}
+	if err := l.m.store.Release(ctx, l.serial, l.m.opts.Owner); err != nil {
+		return fmt.Errorf("release %s: %w", l.serial, err)
+	}
+	return nil
+}
+
+func contains(arr []string, v string) bool {
+	for _, x := range arr {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package lease
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, errors.New("file locks are not supported on this platform")
+}
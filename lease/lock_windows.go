@@ -0,0 +1,32 @@
+//go:build windows
+
+package lease
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+	lockfileExclusiveLock   = 0x2
+	lockfileFailImmediately = 0x1
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// tryLockFile takes an exclusive LockFileEx lock on f without blocking and
+// reports whether it got it.
+func tryLockFile(f *os.File) (bool, error) {
+	var ol syscall.Overlapped
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return true, nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,88 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLeaseLost is returned by Renew and Release when the lease expired or was
+// taken by another owner.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Store records which owner holds which device. Implementations must make
+// TryAcquire atomic across every process that shares the store.
+type Store interface {
+	// TryAcquire gives serial to owner for ttl unless it has an unexpired
+	// lease, including one held by owner itself.
+	TryAcquire(ctx context.Context, serial, owner string, ttl time.Duration) (bool, error)
+	// Renew extends owner's lease on serial by ttl.
+	Renew(ctx context.Context, serial, owner string, ttl time.Duration) error
+	// Release ends owner's lease on serial.
+	Release(ctx context.Context, serial, owner string) error
+}
+
+// Record is a lease as kept by a Store.
+type Record struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+func (r Record) live(now time.Time) bool { return r.Owner != "" && now.Before(r.Expires) }
+
+// MemoryStore keeps leases in process memory; it only protects devices from
+// goroutines of the same program.
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]Record
+}
+
+// NewMemoryStore returns an empty in-process store.
+func NewMemoryStore() *MemoryStore { return &MemoryStore{leases: map[string]Record{}} }
+
+func (s *MemoryStore) TryAcquire(_ context.Context, serial, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if r := s.leases[serial]; r.live(now) {
+		return false, nil
+	}
+	s.leases[serial] = Record{Owner: owner, Expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Renew(_ context.Context, serial, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if r := s.leases[serial]; r.Owner != owner || !r.live(now) {
+		return ErrLeaseLost
+	}
+	s.leases[serial] = Record{Owner: owner, Expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, serial, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r := s.leases[serial]; r.Owner != owner {
+		return ErrLeaseLost
+	}
+	delete(s.leases, serial)
+	return nil
+}
+
+// Leases returns the unexpired leases by serial.
+func (s *MemoryStore) Leases() map[string]Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	out := map[string]Record{}
+	for k, r := range s.leases {
+		if r.live(now) {
+			out[k] = r
+		}
+	}
+	return out
+}
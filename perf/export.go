@@ -0,0 +1,54 @@
+package perf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Series is the samples of one app.
+type Series struct {
+	Bundle   string        `json:"bundle"`
+	Interval time.Duration `json:"-"`
+	Samples  []Sample      `json:"samples"`
+}
+
+var csvHeader = []string{"time", "pid", "cpu_percent", "pss_kb", "threads", "fps"}
+
+// WriteCSV writes one row per sample with a header; unknown metrics are -1.
+func (s Series) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, p := range s.Samples {
+		row := []string{
+			p.Time.Format(time.RFC3339Nano),
+			strconv.Itoa(p.PID),
+			formatFloat(p.CPU),
+			strconv.Itoa(p.PSS),
+			strconv.Itoa(p.Threads),
+			formatFloat(p.FPS),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the series as one indented JSON object.
+func (s Series) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Series
+		IntervalMs int64 `json:"intervalMs"`
+	}{s, s.Interval.Milliseconds()})
+}
+
+// formatFloat keeps CSV cells short.
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
@@ -0,0 +1,98 @@
+package perf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// procStat is what a sample needs from /proc/<pid>/stat.
+type procStat struct {
+	// Ticks is utime+stime in clock ticks.
+	Ticks   int64
+	Threads int
+}
+
+// parseProcStat reads utime, stime and num_threads (fields 14, 15 and 20).
+// The command name may contain spaces, so fields are counted after its ')'.
+func parseProcStat(s string) (procStat, bool) {
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return procStat{}, false
+	}
+	f := strings.Fields(s[i+1:])
+	// f[0] is field 3 (state)
+	if len(f) < 18 {
+		return procStat{}, false
+	}
+	utime, err1 := strconv.ParseInt(f[11], 10, 64)
+	stime, err2 := strconv.ParseInt(f[12], 10, 64)
+	threads, err3 := strconv.Atoi(f[17])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return procStat{}, false
+	}
+	return procStat{Ticks: utime + stime, Threads: threads}, true
+}
+
+// parseMemPSS returns the PSS total in kB from the "Total" row of `hidumper --mem <pid>`.
+func parseMemPSS(s string) (int, bool) {
+	for _, l := range strings.Split(s, "\n") {
+		f := strings.Fields(l)
+		if len(f) < 2 || f[0] != "Total" {
+			continue
+		}
+		if v, err := strconv.Atoi(f[1]); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// parseTopCPU returns the %CPU of pid from `top -b -n 1 -p <pid>`, locating
+// the column by the header since toybox builds differ in their columns. Toybox
+// marks the sort column as "S[%CPU]", one header word over two data columns.
+func parseTopCPU(s string, pid int) (float64, bool) {
+	col := -1
+	want := strconv.Itoa(pid)
+	for _, l := range strings.Split(s, "\n") {
+		f := strings.Fields(l)
+		if col < 0 {
+			for i, h := range f {
+				switch {
+				case strings.Trim(h, "[]") == "%CPU":
+					col = i
+				case strings.HasSuffix(h, "[%CPU]"):
+					col = i + 1
+				}
+			}
+			continue
+		}
+		if len(f) > col && f[0] == want {
+			v, err := strconv.ParseFloat(f[col], 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseFrameTimes returns the frame timestamps (ns) of a RenderService fps dump:
+// every line that is a single positive number.
+func parseFrameTimes(s string) []int64 {
+	var out []int64
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		if v, err := strconv.ParseInt(l, 10, 64); err == nil && v > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parsePid returns the first pid printed by `pidof`.
+func parsePid(s string) int {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return 0
+	}
+	pid, _ := strconv.Atoi(f[0])
+	return pid
+}
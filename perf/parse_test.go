@@ -0,0 +1,152 @@
+package perf
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want procStat
+		ok   bool
+	}{
+		{"app", "12345 (com.example.app) S 620 620 0 0 -1 1077936448 52123 0 12 0 1520 340 0 0 20 0 46 0 8923412 8123412480 61234 18446744073709551615 1 1 0 0 0 0 4612 1 1073775864 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0\n",
+			procStat{Ticks: 1860, Threads: 46}, true},
+		{"name with spaces and parens", "4242 (Render (main) thread) R 1 4242 0 0 -1 4194560 10 0 0 0 7 3 0 0 20 0 3 0 100 1000 10 18446744073709551615\n",
+			procStat{Ticks: 10, Threads: 3}, true},
+		{"process gone", "cat: /proc/12345/stat: No such file or directory\n", procStat{}, false},
+		{"truncated", "12345 (com.example.app) S 620 620 0 0\n", procStat{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProcStat(tt.in)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("got %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// memDump is `hidumper --mem <pid>` for an ArkTS app, trimmed to the table.
+const memDump = `
+                       Pss         Shared         Shared        Private        Private           Swap        SwapPss           Heap           Heap           Heap
+                     Total          Clean          Dirty          Clean          Dirty          Total          Total           Size          Alloc           Free
+                    ( kB )         ( kB )         ( kB )         ( kB )         ( kB )         ( kB )         ( kB )         ( kB )         ( kB )         ( kB )
+              ------------------------------------------------------------------------------------------------------------------------------------------------------
+            GL           0              0              0              0              0              0              0              0              0              0
+         Graph        4312              0              0              0           4312              0              0              0              0              0
+        ark ts heap   38214            216          10240              0          31656           1024           1024              0              0              0
+       native heap    29876            112            380              0          29604            850            850          41984          37312           4672
+           .so        21467          38120              0           9012           2132              0              0              0              0              0
+           Total     112233          40210          12004           9096          70112           2100           2100          41984          37312           4672
+
+dma:
+                  Dma
+                 ( kB )
+       Total        0
+`
+
+func TestParseMemPSS(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+		ok   bool
+	}{
+		{"table", memDump, 112233, true},
+		{"no total row", "hidumper: pid 12345 not found\n", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseMemPSS(tt.in)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("got %d, %v; want %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseTopCPU(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		pid  int
+		want float64
+		ok   bool
+	}{
+		{"toybox sort column", `Tasks: 1 total,   0 running,   1 sleeping,   0 stopped,   0 zombie
+  Mem:      5.5G total,      5.2G used,      286M free,      4.0M buffers
+ Swap:      2.0G total,      1.1G used,      903M free,      2.1G cached
+800%cpu  43%user   0%nice  37%sys 716%idle   0%iow   4%irq   0%sirq   0%host
+  PID USER         PR  NI VIRT  RES  SHR S[%CPU] %MEM     TIME+ ARGS
+12345 20010042     10 -10  12G 245M 140M S 23.3   4.3   1:23.45 com.example.app
+`, 12345, 23.3, true},
+		{"separate columns", `  PID USER         PR  NI VIRT  RES  SHR S %CPU %MEM     TIME+ ARGS
+12345 20010042     10 -10  12G 245M 140M R  7.0   4.3   1:23.45 com.example.app
+`, 12345, 7, true},
+		{"other pid", "  PID USER  PR  NI VIRT  RES  SHR S[%CPU] %MEM TIME+ ARGS\n  777 root  20   0  1G  10M   5M S  1.0  0.1 0:01.00 init\n", 12345, 0, false},
+		{"no header", "top: bad -p\n", 12345, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTopCPU(tt.in, tt.pid)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("got %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// fpsDump is `hidumper -s RenderService -a "composer fps"`: the newest 128
+// frame timestamps in ns, with zeros for unused slots.
+const fpsDump = `
+-------------------------------[ability]-------------------------------
+
+
+----------------------------------RenderService----------------------------------
+The fps of screen [Id:0] is:
+0
+0
+95230146520604
+95231963187271
+95232046520604
+95232063187271
+95232079853938
+95232096520604
+`
+
+func TestParseFrameTimes(t *testing.T) {
+	want := []int64{95230146520604, 95231963187271, 95232046520604, 95232063187271, 95232079853938, 95232096520604}
+	if got := parseFrameTimes(fpsDump); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := parseFrameTimes("hidumper: no such layer\n"); got != nil {
+		t.Fatalf("got %v, want none", got)
+	}
+}
+
+func TestMonitorFPS(t *testing.T) {
+	frames := parseFrameTimes(fpsDump)
+	t0 := time.Now()
+	m := &Monitor{}
+	// first sample: the frames within a second of the newest, the oldest one is older
+	if got := m.fps(frames, t0); got != 5 {
+		t.Fatalf("first sample fps = %v, want 5", got)
+	}
+	m.lastAt = t0
+	// the screen did not redraw: the same dump again
+	if got := m.fps(frames, t0.Add(time.Second)); got != 0 {
+		t.Fatalf("no new frames fps = %v, want 0", got)
+	}
+	m.lastAt = t0.Add(time.Second)
+	more := append(frames, 95233096520604, 95233113187271, 95233129853938)
+	if got := m.fps(more, t0.Add(3*time.Second/2)); got != 6 {
+		t.Fatalf("3 new frames in 0.5s fps = %v, want 6", got)
+	}
+	if got := m.fps(nil, t0.Add(2*time.Second)); got != 0 {
+		t.Fatalf("empty dump fps = %v, want 0", got)
+	}
+}
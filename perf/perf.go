@@ -0,0 +1,275 @@
+// Package perf samples the CPU, memory, thread count and frame rate of an app
+// over time through shell commands: /proc/<pid>/stat (top when it cannot be
+// read), `hidumper --mem` and the RenderService fps dump.
+//
+//	m, err := t.StartPerfMonitor(ctx, "com.example.app", time.Second)
+//	// ... drive the app ...
+//	series := m.Stop()
+//	_ = series.WriteCSV(f)
+package perf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the times in /proc/<pid>/stat.
+const clockTicks = 100
+
+// separator splits the outputs of the commands run in one shell call.
+const separator = "---hdckit-perf---"
+
+// Shell runs a command on a device and returns its output; *hdc.Target implements it.
+type Shell interface {
+	ShellOutput(ctx context.Context, command string) (string, error)
+}
+
+// Options configures a Monitor.
+type Options struct {
+	// Interval between samples, default 1s.
+	Interval time.Duration
+	// Layer is the RenderService surface whose frames are counted; empty
+	// counts the frames composed for the whole screen.
+	Layer string
+	// NoMemory skips `hidumper --mem`, which takes a few hundred ms per sample.
+	NoMemory bool
+	// NoFPS skips the frame rate.
+	NoFPS bool
+	// OnSample, if set, sees every sample as it is taken.
+	OnSample func(Sample)
+}
+
+// Sample is one point of the time series. Metrics that could not be read are -1;
+// all are -1 when the app was not running (PID 0).
+type Sample struct {
+	Time time.Time `json:"time"`
+	PID  int       `json:"pid"`
+	// CPU is the app's CPU usage in percent of one core.
+	CPU float64 `json:"cpu"`
+	// PSS is the proportional set size in kB.
+	PSS     int `json:"pssKB"`
+	Threads int `json:"threads"`
+	// FPS is the frame rate since the previous sample.
+	FPS float64 `json:"fps"`
+}
+
+// Monitor samples one app at an interval until stopped.
+type Monitor struct {
+	sh     Shell
+	bundle string
+	opts   Options
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	series Series
+	err    error
+
+	// state carried between samples, only touched by the sampling goroutine
+	pid       int
+	haveTicks bool
+	lastTicks int64
+	lastAt    time.Time
+	lastFrame int64
+}
+
+// Start takes a first sample of bundle and keeps sampling in the background
+// until ctx ends or Stop is called. It fails if the first sample cannot reach
+// the device; an app that is not running yet is sampled as PID 0.
+func Start(ctx context.Context, sh Shell, bundle string, opts Options) (*Monitor, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	m := &Monitor{
+		sh:     sh,
+		bundle: bundle,
+		opts:   opts,
+		done:   make(chan struct{}),
+		series: Series{Bundle: bundle, Interval: opts.Interval},
+	}
+	if err := m.sample(ctx); err != nil {
+		return nil, err
+	}
+	ctx, m.cancel = context.WithCancel(ctx)
+	go m.loop(ctx)
+	return m, nil
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	defer close(m.done)
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.sample(ctx); err != nil && ctx.Err() == nil {
+				m.mu.Lock()
+				m.err = err
+				m.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Series returns a copy of the samples taken so far.
+func (m *Monitor) Series() Series {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series
+	s.Samples = append([]Sample(nil), s.Samples...)
+	return s
+}
+
+// Err returns the last error of a background sample, if any; a failed sample
+// is skipped and sampling goes on.
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Stop ends sampling and returns the series.
+func (m *Monitor) Stop() Series {
+	m.cancel()
+	<-m.done
+	return m.Series()
+}
+
+// sample runs everything in at most two shell calls: pidof, then the stat,
+// memory and fps dumps together.
+func (m *Monitor) sample(ctx context.Context) error {
+	out, err := m.sh.ShellOutput(ctx, "pidof "+m.bundle)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s := Sample{Time: now, PID: parsePid(out), CPU: -1, PSS: -1, Threads: -1, FPS: -1}
+	if s.PID != m.pid {
+		// started, restarted or gone: the CPU baseline belongs to the old process
+		m.pid, m.haveTicks = s.PID, false
+	}
+	if s.PID != 0 {
+		parts, err := m.dump(ctx, s.PID)
+		if err != nil {
+			return err
+		}
+		m.fill(ctx, &s, parts)
+	}
+	m.lastAt = now
+	m.mu.Lock()
+	m.series.Samples = append(m.series.Samples, s)
+	m.mu.Unlock()
+	if m.opts.OnSample != nil {
+		m.opts.OnSample(s)
+	}
+	return nil
+}
+
+func (m *Monitor) dump(ctx context.Context, pid int) ([]string, error) {
+	cmds := []string{fmt.Sprintf("cat /proc/%d/stat", pid)}
+	if !m.opts.NoMemory {
+		cmds = append(cmds, fmt.Sprintf("hidumper --mem %d", pid))
+	}
+	if !m.opts.NoFPS {
+		cmds = append(cmds, fpsCommand(m.opts.Layer))
+	}
+	out, err := m.sh.ShellOutput(ctx, strings.Join(cmds, "; echo "+separator+"; "))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(out, separator), nil
+}
+
+func (m *Monitor) fill(ctx context.Context, s *Sample, parts []string) {
+	next := func() string {
+		if len(parts) == 0 {
+			return ""
+		}
+		p := parts[0]
+		parts = parts[1:]
+		return p
+	}
+	if st, ok := parseProcStat(next()); ok {
+		s.Threads = st.Threads
+		if m.haveTicks {
+			if dt := s.Time.Sub(m.lastAt).Seconds(); dt > 0 {
+				s.CPU = float64(st.Ticks-m.lastTicks) / clockTicks / dt * 100
+			}
+		}
+		m.lastTicks, m.haveTicks = st.Ticks, true
+	} else if out, err := m.sh.ShellOutput(ctx, fmt.Sprintf("top -b -n 1 -p %d", s.PID)); err == nil {
+		if v, ok := parseTopCPU(out, s.PID); ok {
+			s.CPU = v
+		}
+	}
+	if !m.opts.NoMemory {
+		if pss, ok := parseMemPSS(next()); ok {
+			s.PSS = pss
+		}
+	}
+	if !m.opts.NoFPS {
+		s.FPS = m.fps(parseFrameTimes(next()), s.Time)
+	}
+}
+
+// fps counts the frames newer than the last sample's newest. The dump only
+// keeps the latest 128 frames, so long intervals undercount busy screens.
+func (m *Monitor) fps(frames []int64, now time.Time) float64 {
+	if len(frames) == 0 {
+		return 0
+	}
+	var newest int64
+	for _, f := range frames {
+		newest = max(newest, f)
+	}
+	if m.lastFrame == 0 {
+		// first sample: frames in the second up to the newest one
+		m.lastFrame = newest
+		n := 0
+		for _, f := range frames {
+			if f > newest-int64(time.Second) {
+				n++
+			}
+		}
+		return float64(n)
+	}
+	n := 0
+	for _, f := range frames {
+		if f > m.lastFrame {
+			n++
+		}
+	}
+	m.lastFrame = max(m.lastFrame, newest)
+	dt := now.Sub(m.lastAt).Seconds()
+	if dt <= 0 {
+		return 0
+	}
+	return float64(n) / dt
+}
+
+func fpsCommand(layer string) string {
+	if layer == "" {
+		return `hidumper -s RenderService -a "composer fps"`
+	}
+	return `hidumper -s RenderService -a "fps ` + layer + `"`
+}
+
+func (s Sample) String() string {
+	if s.PID == 0 {
+		return s.Time.Format("15:04:05") + " not running"
+	}
+	return fmt.Sprintf("%s pid=%d cpu=%s pss=%s threads=%s fps=%s", s.Time.Format("15:04:05"), s.PID,
+		metric(s.CPU, "%.1f%%"), metric(float64(s.PSS), "%.0fkB"), metric(float64(s.Threads), "%.0f"), metric(s.FPS, "%.1f"))
+}
+
+func metric(v float64, format string) string {
+	if v < 0 {
+		return "-"
+	}
+	return fmt.Sprintf(format, v)
+}
@@ -0,0 +1,369 @@
+package perf
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChargeState is the battery charging state reported by BatteryService.
+type ChargeState int
+
+const (
+	ChargeNone ChargeState = iota
+	ChargeCharging
+	ChargeDisabled
+	ChargeFull
+
+	// ChargeUnknown is a chargingStatus the BatteryService reports beyond the
+	// states above.
+	ChargeUnknown ChargeState = -1
+)
+
+func (c ChargeState) String() string {
+	switch c {
+	case ChargeNone:
+		return "none"
+	case ChargeCharging:
+		return "charging"
+	case ChargeDisabled:
+		return "disabled"
+	case ChargeFull:
+		return "full"
+	}
+	return "unknown"
+}
+
+func (c ChargeState) MarshalText() ([]byte, error) { return []byte(c.String()), nil }
+
+func (c *ChargeState) UnmarshalText(b []byte) error {
+	if string(b) == ChargeUnknown.String() {
+		*c = ChargeUnknown
+		return nil
+	}
+	for s := ChargeNone; s <= ChargeFull; s++ {
+		if s.String() == string(b) {
+			*c = s
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown charge state %q", b)
+}
+
+// BatteryInfo is parsed from `hidumper -s BatteryService -a -i`. Fields the
+// device did not report are zero.
+type BatteryInfo struct {
+	// Level is the charge in percent.
+	Level  int         `json:"level"`
+	Charge ChargeState `json:"charge"`
+	// Plugged is the power source: "none", "ac", "usb" or "wireless".
+	Plugged string `json:"plugged"`
+	// Temperature is in °C.
+	Temperature float64 `json:"temperature"`
+	// Voltage is in V.
+	Voltage float64 `json:"voltage"`
+	// CurrentMA is the current in mA, negative while discharging.
+	CurrentMA int    `json:"currentMA"`
+	Health    int    `json:"health"`
+	Tech      string `json:"technology,omitempty"`
+}
+
+// Charging reports whether the battery is being charged.
+func (b BatteryInfo) Charging() bool { return b.Charge == ChargeCharging }
+
+// ReadBattery reads the battery state with one shell command.
+func ReadBattery(ctx context.Context, sh Shell) (BatteryInfo, error) {
+	out, err := sh.ShellOutput(ctx, "hidumper -s BatteryService -a -i")
+	if err != nil {
+		return BatteryInfo{}, err
+	}
+	b, ok := parseBattery(out)
+	if !ok {
+		return BatteryInfo{}, fmt.Errorf("no battery state in BatteryService dump: %q", firstLine(out))
+	}
+	return b, nil
+}
+
+func parseBattery(s string) (BatteryInfo, bool) {
+	var b BatteryInfo
+	found := false
+	for _, l := range strings.Split(s, "\n") {
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		n, err := strconv.Atoi(v)
+		if err != nil && k != "technology" {
+			continue
+		}
+		switch k {
+		case "capacity":
+			b.Level, found = n, true
+		case "chargingStatus":
+			b.Charge = ChargeState(n)
+			if b.Charge < ChargeNone || b.Charge > ChargeFull {
+				b.Charge = ChargeUnknown
+			}
+		case "pluggedType":
+			b.Plugged = pluggedType(n)
+		case "temperature":
+			// tenths of a degree
+			b.Temperature = float64(n) / 10
+		case "voltage":
+			// µV
+			b.Voltage = float64(n) / 1e6
+		case "nowCurrent":
+			b.CurrentMA = n
+		case "healthState":
+			b.Health = n
+		case "technology":
+			b.Tech = v
+		}
+	}
+	return b, found
+}
+
+func pluggedType(n int) string {
+	switch n {
+	case 0:
+		return "none"
+	case 1:
+		return "ac"
+	case 2:
+		return "usb"
+	case 3:
+		return "wireless"
+	}
+	return "unknown"
+}
+
+// ThermalZone is one kernel thermal zone.
+type ThermalZone struct {
+	Name string `json:"name"`
+	// Temperature is in °C.
+	Temperature float64 `json:"temperature"`
+}
+
+// ThermalInfo is the device's temperatures and thermal level.
+type ThermalInfo struct {
+	Zones []ThermalZone `json:"zones"`
+	// Level is the ThermalService level (0 cool, higher means throttling),
+	// -1 when the service does not report it.
+	Level int `json:"level"`
+}
+
+// Max returns the hottest zone; the zero zone when there are none.
+func (t ThermalInfo) Max() ThermalZone {
+	var hot ThermalZone
+	for i, z := range t.Zones {
+		if i == 0 || z.Temperature > hot.Temperature {
+			hot = z
+		}
+	}
+	return hot
+}
+
+// Zone returns the temperature of the zone called name.
+func (t ThermalInfo) Zone(name string) (float64, bool) {
+	for _, z := range t.Zones {
+		if z.Name == name {
+			return z.Temperature, true
+		}
+	}
+	return 0, false
+}
+
+// thermalCommand prints "<type> <millidegrees>" per zone, then the ThermalService dump.
+const thermalCommand = `for z in /sys/class/thermal/thermal_zone*; do echo "zone $(cat $z/type) $(cat $z/temp)"; done 2>/dev/null; ` +
+	`echo ` + separator + `; hidumper -s ThermalService -a -i`
+
+// ReadThermal reads the thermal zones and level with one shell command.
+func ReadThermal(ctx context.Context, sh Shell) (ThermalInfo, error) {
+	out, err := sh.ShellOutput(ctx, thermalCommand)
+	if err != nil {
+		return ThermalInfo{}, err
+	}
+	zones, service, _ := strings.Cut(out, separator)
+	t := ThermalInfo{Zones: parseThermalZones(zones), Level: parseThermalLevel(service)}
+	if len(t.Zones) == 0 && t.Level < 0 {
+		return t, fmt.Errorf("no thermal zones or level found: %q", firstLine(out))
+	}
+	return t, nil
+}
+
+func parseThermalZones(s string) []ThermalZone {
+	var out []ThermalZone
+	for _, l := range strings.Split(s, "\n") {
+		f := strings.Fields(l)
+		if len(f) != 3 || f[0] != "zone" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f[2], 64)
+		if err != nil {
+			continue
+		}
+		// most kernels report millidegrees, some whole degrees; no zone is
+		// plausibly beyond ±200 °C, so larger readings are millidegrees
+		if v > 200 || v < -200 {
+			v /= 1000
+		}
+		out = append(out, ThermalZone{Name: f[1], Temperature: v})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// reThermalLevel matches a line that is only the level, e.g. "Thermal level: 2",
+// not other fields ending in level such as "batteryLevel: 80".
+var reThermalLevel = regexp.MustCompile(`(?im)^\s*(?:thermal\s*)?level\s*[:=]\s*(-?\d+)\s*$`)
+
+func parseThermalLevel(s string) int {
+	if m := reThermalLevel.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return -1
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// PowerSample is one point of a PowerMonitor. A part that could not be read
+// is left zero and its error recorded in Err.
+type PowerSample struct {
+	Time    time.Time   `json:"time"`
+	Battery BatteryInfo `json:"battery"`
+	Thermal ThermalInfo `json:"thermal"`
+	Err     string      `json:"error,omitempty"`
+}
+
+// PowerMonitor records battery and thermal state at an interval, to correlate
+// failures with heat and throttling over long runs.
+type PowerMonitor struct {
+	sh       Shell
+	interval time.Duration
+	onSample func(PowerSample)
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu      sync.Mutex
+	samples []PowerSample
+}
+
+// StartPower takes a first sample and keeps sampling every interval (default
+// 30s) until ctx ends or Stop is called. onSample may be nil.
+func StartPower(ctx context.Context, sh Shell, interval time.Duration, onSample func(PowerSample)) (*PowerMonitor, error) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	m := &PowerMonitor{sh: sh, interval: interval, onSample: onSample, done: make(chan struct{})}
+	if s, failed := m.sample(ctx); failed {
+		return nil, fmt.Errorf("power sample: %s", s.Err)
+	}
+	ctx, m.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.sample(ctx)
+			}
+		}
+	}()
+	return m, nil
+}
+
+// sample records one sample and reports whether nothing could be read.
+func (m *PowerMonitor) sample(ctx context.Context) (PowerSample, bool) {
+	s := PowerSample{Time: time.Now()}
+	var errs []string
+	var err error
+	if s.Battery, err = ReadBattery(ctx, m.sh); err != nil {
+		errs = append(errs, "battery: "+err.Error())
+	}
+	if s.Thermal, err = ReadThermal(ctx, m.sh); err != nil {
+		errs = append(errs, "thermal: "+err.Error())
+	}
+	if ctx.Err() != nil {
+		// cut short by Stop; not a device problem
+		return s, false
+	}
+	s.Err = strings.Join(errs, "; ")
+	m.mu.Lock()
+	m.samples = append(m.samples, s)
+	m.mu.Unlock()
+	if m.onSample != nil {
+		m.onSample(s)
+	}
+	return s, len(errs) == 2
+}
+
+// Samples returns a copy of the samples taken so far.
+func (m *PowerMonitor) Samples() []PowerSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]PowerSample(nil), m.samples...)
+}
+
+// Stop ends sampling and returns the samples.
+func (m *PowerMonitor) Stop() []PowerSample {
+	m.cancel()
+	<-m.done
+	return m.Samples()
+}
+
+var powerCSVHeader = []string{"time", "battery_level", "charge", "plugged", "battery_temp_c", "voltage_v", "current_ma", "thermal_level", "max_zone", "max_zone_temp_c", "error"}
+
+// WritePowerCSV writes one row per sample; the hottest zone stands for all zones.
+func WritePowerCSV(w io.Writer, samples []PowerSample) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(powerCSVHeader); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		hot := s.Thermal.Max()
+		row := []string{
+			s.Time.Format(time.RFC3339Nano),
+			strconv.Itoa(s.Battery.Level),
+			s.Battery.Charge.String(),
+			s.Battery.Plugged,
+			formatFloat(s.Battery.Temperature),
+			strconv.FormatFloat(s.Battery.Voltage, 'f', 3, 64),
+			strconv.Itoa(s.Battery.CurrentMA),
+			strconv.Itoa(s.Thermal.Level),
+			hot.Name,
+			formatFloat(hot.Temperature),
+			s.Err,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePowerJSON writes the samples as an indented JSON array.
+func WritePowerJSON(w io.Writer, samples []PowerSample) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(samples)
+}
@@ -0,0 +1,127 @@
+package perf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// batteryDump is `hidumper -s BatteryService -a -i` from a phone on USB.
+const batteryDump = `
+-------------------------------[ability]-------------------------------
+
+
+----------------------------------BatteryService---------------------------------
+capacity: 87
+batteryLevel: 2
+chargingStatus: 1
+healthState: 1
+pluggedType: 2
+voltage: 4211000
+present: 1
+technology: Li-poly
+nowCurrent: 1342
+currentAverage: 1190
+totalEnergy: 4500000
+remainingEnergy: 3915000
+remainingChargeTime: 2580
+temperature: 312
+chargeType: 1
+`
+
+func TestParseBattery(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want BatteryInfo
+		ok   bool
+	}{
+		{"charging on usb", batteryDump, BatteryInfo{
+			Level: 87, Charge: ChargeCharging, Plugged: "usb", Temperature: 31.2,
+			Voltage: 4.211, CurrentMA: 1342, Health: 1, Tech: "Li-poly",
+		}, true},
+		{"discharging", "capacity: 40\nchargingStatus: 0\npluggedType: 0\nnowCurrent: -420\ntemperature: 265\n", BatteryInfo{
+			Level: 40, Charge: ChargeNone, Plugged: "none", CurrentMA: -420, Temperature: 26.5,
+		}, true},
+		{"unlisted charging status", "capacity: 100\nchargingStatus: 7\npluggedType: 9\n", BatteryInfo{
+			Level: 100, Charge: ChargeUnknown, Plugged: "unknown",
+		}, true},
+		{"service missing", "hidumper: No such service ability\n", BatteryInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseBattery(tt.in)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseBattery = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestChargeStateJSONRoundTrip(t *testing.T) {
+	for _, c := range []ChargeState{ChargeNone, ChargeCharging, ChargeDisabled, ChargeFull, ChargeUnknown, ChargeState(9)} {
+		b, err := json.Marshal(BatteryInfo{Charge: c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got BatteryInfo
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%v: decode %s: %v", c, b, err)
+		}
+		want := c
+		if c > ChargeFull {
+			want = ChargeUnknown
+		}
+		if got.Charge != want {
+			t.Errorf("%s: got %v, want %v", b, got.Charge, want)
+		}
+	}
+}
+
+func TestParseThermalZones(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []ThermalZone
+	}{
+		{"millidegrees", "zone soc_thermal 45200\nzone battery 31000\n",
+			[]ThermalZone{{"battery", 31}, {"soc_thermal", 45.2}}},
+		{"whole degrees", "zone shell_front 38\nzone ambient 24\n",
+			[]ThermalZone{{"ambient", 24}, {"shell_front", 38}}},
+		{"mixed units per zone", "zone cpu 52000\nzone pa 41\nzone modem 500\n",
+			[]ThermalZone{{"cpu", 52}, {"modem", 0.5}, {"pa", 41}}},
+		{"below zero", "zone battery -8000\nzone outdoor -12\n",
+			[]ThermalZone{{"battery", -8}, {"outdoor", -12}}},
+		{"unreadable zones skipped", "zone gpu \nzone npu N/A\ncat: /sys/class/thermal/thermal_zone9/temp: Permission denied\nzone lcd 29500\n",
+			[]ThermalZone{{"lcd", 29.5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseThermalZones(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseThermalLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"thermal level line", "----ThermalService----\nThermal level: 2\n", 2},
+		{"bare level", "name: soc temperature: 45000\nlevel: 1\n", 1},
+		{"crlf", "Level = 3\r\n", 3},
+		{"battery level first", "batteryLevel: 80\nscreenLevel: 5\nThermal level: 0\n", 0},
+		{"only other levels", "batteryLevel: 80\nfan level mode: 4 steps\n", -1},
+		{"not reported", "hidumper: No such service ability\n", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseThermalLevel(tt.in); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
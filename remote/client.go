@@ -0,0 +1,154 @@
+// Package remote talks to a hdckit HTTP server (package server, `hdccli serve`)
+// and implements hdc.Device and hdc.UiController on top of it, so tests written
+// against those interfaces run on a USB device or on a lab host alike.
+//
+//	c := remote.NewClient("http://lab:9700", "secret")
+//	var dev hdc.Device = c.Device("SERIAL")
+//	var ui hdc.UiController = c.UiDriver("SERIAL")
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+// Error is a failed request, carrying the server's status and message.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("remote: %s (%d)", e.Message, e.Status) }
+
+// Is maps server errors back to the local sentinels, so errors.Is checks keep
+// working: hdc.ErrComponentNotFound, hdc.ErrWindowNotFound, hdc.ErrRPCClosed
+// and context.DeadlineExceeded.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case hdc.ErrComponentNotFound, hdc.ErrWindowNotFound:
+		return e.Status == http.StatusNotFound && strings.Contains(e.Message, target.Error())
+	case hdc.ErrRPCClosed:
+		return e.Status == http.StatusBadGateway
+	case context.DeadlineExceeded:
+		return e.Status == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// Client is a connection to one hdckit server.
+type Client struct {
+	base  string
+	token string
+	// HTTP is the client used for requests; streams are not subject to its Timeout.
+	HTTP *http.Client
+}
+
+// NewClient returns a client for the server at baseURL (e.g. http://lab:9700).
+// token may be empty when the server has none.
+func NewClient(baseURL, token string) *Client {
+	return &Client{base: strings.TrimRight(baseURL, "/"), token: token, HTTP: http.DefaultClient}
+}
+
+// ListTargets returns the serials connected to the server's host.
+func (c *Client) ListTargets(ctx context.Context) ([]string, error) {
+	var out []string
+	err := c.do(ctx, http.MethodGet, "/api/targets", nil, nil, &out)
+	return out, err
+}
+
+// Device returns the remote device with serial.
+func (c *Client) Device(serial string) *Device { return &Device{c: c, serial: serial} }
+
+// UiDriver returns a UI controller for the remote device with serial.
+func (c *Client) UiDriver(serial string) *UiDriver { return &UiDriver{c: c, serial: serial} }
+
+func (c *Client) url(path string, q url.Values) string {
+	u := c.base + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+func targetPath(serial, op string) string {
+	return "/api/targets/" + url.PathEscape(serial) + "/" + op
+}
+
+// request sends body (encoded as JSON unless it is an io.Reader) and returns
+// the response, or an *Error for non-2xx statuses.
+func (c *Client) request(ctx context.Context, method, path string, q url.Values, body any) (*http.Response, error) {
+	var rd io.Reader
+	ctype := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		rd, ctype = b, "application/octet-stream"
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		rd, ctype = bytes.NewReader(buf), "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, q), rd)
+	if err != nil {
+		return nil, err
+	}
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var eb api.ErrorBody
+		msg := strings.TrimSpace(string(b))
+		if json.Unmarshal(b, &eb) == nil && eb.Error != "" {
+			msg = eb.Error
+		}
+		return nil, &Error{Status: resp.StatusCode, Message: msg}
+	}
+	return resp, nil
+}
+
+// do runs a request and decodes a JSON reply into out, if out is not nil.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body, out any) error {
+	resp, err := c.request(ctx, method, path, q, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("remote: decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// readAll runs a request and returns the raw reply.
+func (c *Client) readAll(ctx context.Context, method, path string, q url.Values) ([]byte, error) {
+	resp, err := c.request(ctx, method, path, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+	"github.com/airhandsome/hdckit-go/perf"
+)
+
+// Device is a device on a hdckit server; it implements hdc.Device.
+type Device struct {
+	c      *Client
+	serial string
+}
+
+var _ hdc.Device = (*Device)(nil)
+
+func (d *Device) Key() string { return d.serial }
+
+func (d *Device) path(op string) string { return targetPath(d.serial, op) }
+
+func (d *Device) GetParameters(ctx context.Context) (map[string]string, error) {
+	var p map[string]string
+	err := d.c.do(ctx, http.MethodGet, d.path("params"), nil, nil, &p)
+	return p, err
+}
+
+// BatteryInfo returns the device's battery state, as hdc.Target.BatteryInfo.
+func (d *Device) BatteryInfo(ctx context.Context) (perf.BatteryInfo, error) {
+	var b perf.BatteryInfo
+	err := d.c.do(ctx, http.MethodGet, d.path("battery"), nil, nil, &b)
+	return b, err
+}
+
+// ThermalInfo returns the device's temperatures, as hdc.Target.ThermalInfo.
+func (d *Device) ThermalInfo(ctx context.Context) (perf.ThermalInfo, error) {
+	var th perf.ThermalInfo
+	err := d.c.do(ctx, http.MethodGet, d.path("thermal"), nil, nil, &th)
+	return th, err
+}
+
+func (d *Device) ShellOutput(ctx context.Context, command string) (string, error) {
+	var out api.ShellResponse
+	err := d.c.do(ctx, http.MethodPost, d.path("shell"), nil, api.ShellRequest{Cmd: command}, &out)
+	return out.Output, err
+}
+
+// SendFile uploads local and pushes it to remote on the device.
+func (d *Device) SendFile(ctx context.Context, local, remote string) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.c.do(ctx, http.MethodPut, d.path("file"), url.Values{"remote": {remote}}, f, nil)
+}
+
+// RecvFile pulls remote from the device into local.
+func (d *Device) RecvFile(ctx context.Context, remote, local string) error {
+	resp, err := d.c.request(ctx, http.MethodGet, d.path("file"), url.Values{"remote": {remote}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	f, err := os.Create(local)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Install uploads hap and installs it.
+func (d *Device) Install(ctx context.Context, hap string) error {
+	f, err := os.Open(hap)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.c.do(ctx, http.MethodPost, d.path("install"), url.Values{"name": {filepath.Base(hap)}}, f, nil)
+}
+
+func (d *Device) Uninstall(ctx context.Context, bundle string) error {
+	return d.c.do(ctx, http.MethodPost, d.path("uninstall"), nil, api.UninstallRequest{Bundle: bundle}, nil)
+}
+
+// Forward forwards on the server's host: local is a port there, not on the caller's machine.
+func (d *Device) Forward(ctx context.Context, local, remote string) error {
+	return d.c.do(ctx, http.MethodPost, d.path("forward"), nil, api.ForwardRequest{Local: local, Remote: remote}, nil)
+}
+
+func (d *Device) RemoveForward(ctx context.Context, local, remote string) error {
+	return d.c.do(ctx, http.MethodDelete, d.path("forward"), url.Values{"local": {local}, "remote": {remote}}, nil, nil)
+}
+
+func (d *Device) ListForwards(ctx context.Context) ([]hdc.Forward, error) {
+	var fs []hdc.Forward
+	err := d.c.do(ctx, http.MethodGet, d.path("forward"), nil, nil, &fs)
+	return fs, err
+}
+
+// Hilog streams the device log until ctx ends or the returned reader is closed.
+func (d *Device) Hilog(ctx context.Context, clear bool) (io.ReadCloser, error) {
+	q := url.Values{}
+	if clear {
+		q.Set("clear", "1")
+	}
+	resp, err := d.c.request(ctx, http.MethodGet, d.path("hilog"), q, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Capture calls fn with every JPEG frame of the screen stream until ctx ends.
+// fps <= 0 leaves the rate to the device; scale in (0,1) shrinks frames.
+func (d *Device) Capture(ctx context.Context, fps int, scale float64, fn func(frame []byte)) error {
+	q := url.Values{}
+	if fps > 0 {
+		q.Set("fps", strconv.Itoa(fps))
+	}
+	if scale > 0 {
+		q.Set("scale", strconv.FormatFloat(scale, 'f', -1, 64))
+	}
+	resp, err := d.c.request(ctx, http.MethodGet, d.path("capture"), q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("remote: capture: %w", err)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		fn(b)
+	}
+}
@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+// UiDriver drives the UI of a device on a hdckit server; it implements
+// hdc.UiController. The server owns the uitest session.
+type UiDriver struct {
+	c      *Client
+	serial string
+}
+
+var _ hdc.UiController = (*UiDriver)(nil)
+
+func (u *UiDriver) path(op string) string { return targetPath(u.serial, op) }
+
+// Start makes the server bring up the device's driver.
+func (u *UiDriver) Start(ctx context.Context) error {
+	_, err := u.GetDisplaySize(ctx)
+	return err
+}
+
+// Stop is a no-op; the server keeps its driver for other clients.
+func (u *UiDriver) Stop() {}
+
+func (u *UiDriver) point(ctx context.Context, op string, x, y int) error {
+	return u.c.do(ctx, http.MethodPost, u.path("ui/"+op), nil, api.PointRequest{X: x, Y: y}, nil)
+}
+
+func (u *UiDriver) Click(ctx context.Context, x, y int) error {
+	return u.point(ctx, "click", x, y)
+}
+
+func (u *UiDriver) TouchDown(ctx context.Context, x, y int) error {
+	return u.point(ctx, "touchDown", x, y)
+}
+
+func (u *UiDriver) TouchMove(ctx context.Context, x, y int) error {
+	return u.point(ctx, "touchMove", x, y)
+}
+
+func (u *UiDriver) TouchUp(ctx context.Context, x, y int) error {
+	return u.point(ctx, "touchUp", x, y)
+}
+
+// Step plays one script step on the server, e.g. a long click or a swipe.
+func (u *UiDriver) Step(ctx context.Context, st hdc.Step) error {
+	return u.c.do(ctx, http.MethodPost, u.path("ui/step"), nil, st, nil)
+}
+
+func (u *UiDriver) Swipe(ctx context.Context, x1, y1, x2, y2 int, dur time.Duration) error {
+	return u.Step(ctx, hdc.Step{Action: hdc.StepSwipe, X: x1, Y: y1, ToX: x2, ToY: y2, DurationMs: int(dur.Milliseconds())})
+}
+
+func (u *UiDriver) PressBack(ctx context.Context) error {
+	return u.Step(ctx, hdc.Step{Action: hdc.StepBack})
+}
+
+// InputText taps (x, y) and types text.
+func (u *UiDriver) InputText(ctx context.Context, text string, x, y int) error {
+	return u.Step(ctx, hdc.Step{Action: hdc.StepInput, Text: text, X: x, Y: y})
+}
+
+func (u *UiDriver) Screenshot(ctx context.Context, opts hdc.ScreenshotOptions) ([]byte, error) {
+	q := url.Values{}
+	if opts.Format != "" {
+		q.Set("format", opts.Format)
+	}
+	if opts.Quality > 0 {
+		q.Set("quality", strconv.Itoa(opts.Quality))
+	}
+	b, err := u.c.readAll(ctx, http.MethodGet, u.path("screenshot"), q)
+	if err != nil || opts.Region.Empty() {
+		return b, err
+	}
+	// the server sends full screens; crop here
+	return hdc.ConvertScreenshot(b, opts)
+}
+
+func (u *UiDriver) CaptureLayout(ctx context.Context) (any, error) {
+	var v any
+	err := u.c.do(ctx, http.MethodGet, u.path("layout"), nil, nil, &v)
+	return v, err
+}
+
+func (u *UiDriver) DumpLayout(ctx context.Context) (*hdc.LayoutNode, error) {
+	v, err := u.CaptureLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hdc.ParseLayout(v)
+}
+
+// FindComponent matches sel against the layout locally; watchers registered on
+// the server's driver do not run.
+func (u *UiDriver) FindComponent(ctx context.Context, sel hdc.Selector) (*hdc.LayoutNode, error) {
+	root, err := u.DumpLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n := root.Find(sel); n != nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("%w: %s", hdc.ErrComponentNotFound, sel)
+}
+
+func (u *UiDriver) ClickComponent(ctx context.Context, sel hdc.Selector) error {
+	n, err := u.FindComponent(ctx, sel)
+	if err != nil {
+		return err
+	}
+	c := n.Center()
+	return u.Click(ctx, c.X, c.Y)
+}
+
+func (u *UiDriver) GetDisplaySize(ctx context.Context) (hdc.DisplaySize, error) {
+	var s hdc.DisplaySize
+	err := u.c.do(ctx, http.MethodGet, u.path("display"), nil, nil, &s)
+	return s, err
+}
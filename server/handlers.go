@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+func (s *Server) params(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	p, err := t.GetParameters(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (s *Server) battery(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	b, err := t.BatteryInfo(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, b)
+}
+
+func (s *Server) thermal(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	th, err := t.ThermalInfo(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, th)
+}
+
+func (s *Server) shell(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	var req api.ShellRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	c, err := t.Shell(r.Context(), req.Cmd)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	out, err := c.ReadAll(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, api.ShellResponse{Output: string(out)})
+}
+
+// file pushes the request body to ?remote= (PUT/POST) or pulls it (GET).
+// hdc transfers files between paths, so both go through a temp file.
+func (s *Server) file(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	remote := r.URL.Query().Get("remote")
+	if remote == "" {
+		writeError(w, http.StatusBadRequest, errors.New("remote is required"))
+		return
+	}
+	dir, err := os.MkdirTemp("", "hdckit-file-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, filepath.Base(remote))
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		if err := saveBody(r, local); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := t.SendFile(r.Context(), local, remote); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if err := t.RecvFile(r.Context(), remote, local); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeFile(w, r, local)
+	default:
+		method(w, r, http.MethodGet, http.MethodPut, http.MethodPost)
+	}
+}
+
+func (s *Server) install(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	dir, err := os.MkdirTemp("", "hdckit-hap-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "app.hap"
+	}
+	hap := filepath.Join(dir, filepath.Base(name))
+	if err := saveBody(r, hap); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := t.Install(r.Context(), hap); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) uninstall(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	var req api.UninstallRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if err := t.Uninstall(r.Context(), req.Bundle); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	switch r.Method {
+	case http.MethodGet:
+		fs, err := t.ListForwards(r.Context())
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, fs)
+		return
+	case http.MethodPost:
+		var req api.ForwardRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if err := t.Forward(r.Context(), req.Local, req.Remote); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+	case http.MethodDelete:
+		q := r.URL.Query()
+		if err := t.RemoveForward(r.Context(), q.Get("local"), q.Get("remote")); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+	default:
+		method(w, r, http.MethodGet, http.MethodPost, http.MethodDelete)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) screenshot(w http.ResponseWriter, r *http.Request, serial string) {
+	d, err := s.Driver(r.Context(), serial)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	q := r.URL.Query()
+	opts := hdc.ScreenshotOptions{Format: q.Get("format")}
+	opts.Quality, _ = strconv.Atoi(q.Get("quality"))
+	b, err := d.Screenshot(r.Context(), opts)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(b))
+	_, _ = w.Write(b)
+}
+
+func (s *Server) layout(w http.ResponseWriter, r *http.Request, serial string) {
+	d, err := s.Driver(r.Context(), serial)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	v, err := d.CaptureLayout(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, v)
+}
+
+func (s *Server) display(w http.ResponseWriter, r *http.Request, serial string) {
+	d, err := s.Driver(r.Context(), serial)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	size, err := d.GetDisplaySize(r.Context())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, size)
+}
+
+func (s *Server) ui(w http.ResponseWriter, r *http.Request, serial, op string) {
+	d, err := s.Driver(r.Context(), serial)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	ctx := r.Context()
+	switch op {
+	case "click", "touchDown", "touchMove", "touchUp":
+		var p api.PointRequest
+		if !decode(w, r, &p) {
+			return
+		}
+		fn := map[string]func(context.Context, int, int) error{
+			"click": d.Click, "touchDown": d.TouchDown, "touchMove": d.TouchMove, "touchUp": d.TouchUp,
+		}[op]
+		err = fn(ctx, p.X, p.Y)
+	case "step":
+		var st hdc.Step
+		if !decode(w, r, &st) {
+			return
+		}
+		err = d.PlayScript(ctx, &hdc.Script{Steps: []hdc.Step{st}}, hdc.PlayOptions{NoDelay: true})
+		var se *hdc.StepError
+		if errors.As(err, &se) {
+			err = se.Err
+		}
+	case "find":
+		var sel hdc.Selector
+		if !decode(w, r, &sel) {
+			return
+		}
+		n, err := d.FindComponent(ctx, sel)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		b := n.Bounds()
+		writeJSON(w, api.NodeResponse{Bounds: [4]int{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y}, Attributes: n.Attributes})
+		return
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown ui operation %q", op))
+		return
+	}
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func saveBody(r *http.Request, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
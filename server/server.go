@@ -0,0 +1,295 @@
+// Package server exposes the devices of an hdc Client over an HTTP JSON API,
+// so test runners on other machines can drive devices plugged into a lab host.
+//
+//	srv := server.New(server.Options{Client: hdc.NewClient(hdc.Options{})})
+//	defer srv.Close()
+//	http.ListenAndServe(":9700", srv)
+//
+// Routes, all under /api (serial is the hdc connect key):
+//
+//	GET    /targets                        ["serial", ...]
+//	GET    /targets/{serial}/params        device parameters
+//	GET    /targets/{serial}/battery       perf.BatteryInfo
+//	GET    /targets/{serial}/thermal       perf.ThermalInfo
+//	POST   /targets/{serial}/shell         {"cmd"} -> {"output"}
+//	PUT    /targets/{serial}/file?remote=  body -> pushed to remote
+//	GET    /targets/{serial}/file?remote=  pulled file
+//	POST   /targets/{serial}/install       body is the .hap
+//	POST   /targets/{serial}/uninstall     {"bundle"}
+//	GET    /targets/{serial}/forward       [{"target","local","remote"}]
+//	POST   /targets/{serial}/forward       {"local","remote"}
+//	DELETE /targets/{serial}/forward?local=&remote=
+//	GET    /targets/{serial}/screenshot?format=png|jpeg&quality=
+//	GET    /targets/{serial}/layout        CaptureLayout JSON
+//	GET    /targets/{serial}/display       {"width","height"}
+//	POST   /targets/{serial}/ui/{op}       click, touchDown, touchMove, touchUp: {"x","y"}
+//	POST   /targets/{serial}/ui/step       a script step (hdc.Step): swipe, input, back, ...
+//	POST   /targets/{serial}/ui/find       hdc.Selector -> {"bounds","attributes"}
+//	GET    /targets/{serial}/hilog?clear=1 streamed text
+//	GET    /targets/{serial}/capture?scale=&fps=  MJPEG stream
+//
+// JSON bodies must be sent as Content-Type: application/json, and browser
+// requests from another origin are refused.
+//
+// Errors are {"error": "..."} with a matching status code.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/airhandsome/hdckit-go/api"
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+// Options configures a Server.
+type Options struct {
+	Client *hdc.Client
+	// Driver configures the UiDriver created for each device.
+	Driver hdc.UiDriverOptions
+	// Token, if set, must be sent as "Authorization: Bearer <token>". The
+	// hilog and capture streams, which browsers open without headers, also
+	// take ?token=; query strings end up in access logs and proxy logs, so
+	// prefer the header wherever the client can set one.
+	Token string
+}
+
+// Server is an http.Handler serving the API. UiDrivers are started on first
+// use and kept until Close.
+type Server struct {
+	opts Options
+
+	mu      sync.Mutex
+	drivers map[string]*hdc.UiDriver
+}
+
+// New returns a server for opts.Client.
+func New(opts Options) *Server {
+	if opts.Client == nil {
+		opts.Client = hdc.NewClient(hdc.Options{})
+	}
+	return &Server{opts: opts, drivers: map[string]*hdc.UiDriver{}}
+}
+
+// Client returns the hdc client the server drives.
+func (s *Server) Client() *hdc.Client { return s.opts.Client }
+
+// Driver returns the started UiDriver of serial, creating it on first use.
+func (s *Server) Driver(ctx context.Context, serial string) (*hdc.UiDriver, error) {
+	s.mu.Lock()
+	d, ok := s.drivers[serial]
+	if !ok {
+		d = s.opts.Client.Target(serial).CreateUiDriverWithOptions(s.opts.Driver)
+		s.drivers[serial] = d
+	}
+	s.mu.Unlock()
+	// Start is a no-op on a live driver and reconnects a dead one
+	if err := d.Start(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Close stops every UiDriver.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, d := range s.drivers {
+		d.Stop()
+		delete(s.drivers, k)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/")
+	parts := strings.Split(path, "/")
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+		return
+	}
+	if !s.authorized(r, parts) {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	if parts[0] != "targets" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route %s", r.URL.Path))
+		return
+	}
+	if len(parts) == 1 {
+		if !method(w, r, http.MethodGet) {
+			return
+		}
+		ts, err := s.opts.Client.ListTargets(r.Context())
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, ts)
+		return
+	}
+	if len(parts) < 3 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route %s", r.URL.Path))
+		return
+	}
+	s.serveTarget(w, r, parts[1], strings.Join(parts[2:], "/"))
+}
+
+// Authorized reports whether r carries token as "Authorization: Bearer <token>".
+// An empty token authorizes every request.
+func Authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && tokenEqual(got, token)
+}
+
+func tokenEqual(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// sameOrigin reports whether r comes from a page served by this host, or from
+// a client that is not a browser and sends no Origin. Without it, any web page
+// the operator opens could POST to a tokenless server on loopback.
+func sameOrigin(r *http.Request) bool {
+	o := r.Header.Get("Origin")
+	if o == "" {
+		return true
+	}
+	u, err := url.Parse(o)
+	return err == nil && u.Host == r.Host
+}
+
+// authorized checks the header, or ?token= on the streaming GETs.
+func (s *Server) authorized(r *http.Request, parts []string) bool {
+	if Authorized(r, s.opts.Token) {
+		return true
+	}
+	stream := r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "targets" &&
+		(parts[2] == "hilog" || parts[2] == "capture")
+	return stream && tokenEqual(r.URL.Query().Get("token"), s.opts.Token)
+}
+
+func (s *Server) serveTarget(w http.ResponseWriter, r *http.Request, serial, op string) {
+	t := s.opts.Client.Target(serial)
+	switch op {
+	case "params":
+		if method(w, r, http.MethodGet) {
+			s.params(w, r, t)
+		}
+	case "battery":
+		if method(w, r, http.MethodGet) {
+			s.battery(w, r, t)
+		}
+	case "thermal":
+		if method(w, r, http.MethodGet) {
+			s.thermal(w, r, t)
+		}
+	case "shell":
+		if method(w, r, http.MethodPost) {
+			s.shell(w, r, t)
+		}
+	case "file":
+		s.file(w, r, t)
+	case "install":
+		if method(w, r, http.MethodPost) {
+			s.install(w, r, t)
+		}
+	case "uninstall":
+		if method(w, r, http.MethodPost) {
+			s.uninstall(w, r, t)
+		}
+	case "forward":
+		s.forward(w, r, t)
+	case "screenshot":
+		if method(w, r, http.MethodGet) {
+			s.screenshot(w, r, serial)
+		}
+	case "layout":
+		if method(w, r, http.MethodGet) {
+			s.layout(w, r, serial)
+		}
+	case "display":
+		if method(w, r, http.MethodGet) {
+			s.display(w, r, serial)
+		}
+	case "hilog":
+		if method(w, r, http.MethodGet) {
+			s.hilog(w, r, t)
+		}
+	case "capture":
+		if method(w, r, http.MethodGet) {
+			s.capture(w, r, serial)
+		}
+	default:
+		if ui, ok := strings.CutPrefix(op, "ui/"); ok {
+			if method(w, r, http.MethodPost) {
+				s.ui(w, r, serial, ui)
+			}
+			return
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route %s", r.URL.Path))
+	}
+}
+
+// method reports whether r uses one of allowed, answering 405 with the full
+// Allow list otherwise.
+func method(w http.ResponseWriter, r *http.Request, allowed ...string) bool {
+	for _, m := range allowed {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+	return false
+}
+
+// decode reads a JSON body. It insists on the JSON content type, which
+// browsers cannot send cross-origin without a preflight.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("Content-Type must be application/json"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("bad request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(api.ErrorBody{Error: err.Error()})
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, hdc.ErrComponentNotFound), errors.Is(err, hdc.ErrWindowNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, hdc.ErrRPCClosed):
+		return http.StatusBadGateway
+	}
+	var exc *hdc.RPCException
+	if errors.As(err, &exc) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+func newTestServer() *Server {
+	return New(Options{Client: hdc.NewClient(hdc.Options{})})
+}
+
+func TestShellRejectsCrossSiteRequests(t *testing.T) {
+	s := newTestServer()
+	tests := []struct {
+		name   string
+		ctype  string
+		origin string
+		want   int
+	}{
+		{"text/plain simple request", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"form simple request", "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"foreign origin", "text/plain", "https://evil.example", http.StatusForbidden},
+		{"foreign origin with json", "application/json", "http://evil.example", http.StatusForbidden},
+		{"null origin", "application/json", "null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9700/api/targets/SERIAL/shell", strings.NewReader(`{"cmd":"reboot"}`))
+			r.Header.Set("Content-Type", tt.ctype)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestFileRejectsCrossSitePush(t *testing.T) {
+	s := newTestServer()
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9700/api/targets/SERIAL/file?remote=/data/x", strings.NewReader("payload"))
+	r.Header.Set("Content-Type", "text/plain")
+	r.Header.Set("Origin", "https://evil.example")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestMethodNotAllowedListsEveryMethod(t *testing.T) {
+	s := newTestServer()
+	tests := []struct {
+		path  string
+		allow string
+	}{
+		{"/api/targets/SERIAL/file?remote=/data/x", "GET, PUT, POST"},
+		{"/api/targets/SERIAL/forward", "GET, POST, DELETE"},
+		{"/api/targets/SERIAL/shell", "POST"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "http://127.0.0.1:9700"+tt.path, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("%s: status = %d, want 405", tt.path, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s: Allow = %q, want %q", tt.path, got, tt.allow)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	hdc "github.com/airhandsome/hdckit-go/hdc"
+)
+
+// hilog streams the device log as plain text until the client goes away.
+func (s *Server) hilog(w http.ResponseWriter, r *http.Request, t *hdc.Target) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	ctx := r.Context()
+	h, err := t.OpenHilog(ctx, r.URL.Query().Get("clear") == "1")
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer h.Close()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		b, err := h.ReadChunk(ctx)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				// headers are gone; the status can only be reported in-band
+				_, _ = io.WriteString(w, "\n[hdckit] hilog: "+err.Error()+"\n")
+			}
+			return
+		}
+		if _, err := w.Write(b); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// capture streams the screen as MJPEG; every request gets its own capture
+// session on the device's shared stream.
+func (s *Server) capture(w http.ResponseWriter, r *http.Request, serial string) {
+	ctx := r.Context()
+	d, err := s.Driver(ctx, serial)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	q := r.URL.Query()
+	fps, _ := strconv.Atoi(q.Get("fps"))
+	scale, _ := strconv.ParseFloat(q.Get("scale"), 64)
+	streamer := hdc.NewMJPEGStreamer(fps)
+	defer streamer.Close()
+	sess, err := d.StartCaptureScreen(ctx, streamer.Publish, scale)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer sess.Stop(context.WithoutCancel(ctx))
+	streamer.ServeHTTP(w, r)
+}
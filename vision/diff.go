@@ -0,0 +1,90 @@
+package vision
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// DiffOptions controls Diff.
+type DiffOptions struct {
+	// Tolerance is the per-channel difference (0-255) still treated as equal.
+	Tolerance uint8
+	// Mask, if set, limits the comparison to pixels where the mask is not fully transparent.
+	// It is aligned with the bounds of the first image.
+	Mask image.Image
+	// Ignore lists regions excluded from the comparison, e.g. clocks or ads.
+	Ignore []image.Rectangle
+}
+
+// DiffResult summarizes a pixel comparison.
+type DiffResult struct {
+	// Compared is the number of pixels that took part in the comparison.
+	Compared int
+	// Different is the number of compared pixels outside tolerance.
+	Different int
+	// Ratio is Different / Compared.
+	Ratio float64
+	// Bounds encloses all differing pixels, relative to the first image.
+	Bounds image.Rectangle
+	// Image highlights differing pixels in red over a dimmed copy of the first image.
+	Image *image.RGBA
+}
+
+// Equal reports whether no compared pixel differs.
+func (r DiffResult) Equal() bool { return r.Different == 0 }
+
+// Diff compares two images of the same size pixel by pixel.
+func Diff(a, b image.Image, opts DiffOptions) (DiffResult, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return DiffResult{}, errors.New("image sizes differ")
+	}
+	tol := int32(opts.Tolerance) * 0x101
+	res := DiffResult{Image: image.NewRGBA(image.Rect(0, 0, ab.Dx(), ab.Dy()))}
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			pa := image.Pt(ab.Min.X+x, ab.Min.Y+y)
+			ar, ag, abl, _ := a.At(pa.X, pa.Y).RGBA()
+			if !included(pa, opts) {
+				res.Image.Set(x, y, color.RGBA{uint8(ar >> 10), uint8(ag >> 10), uint8(abl >> 10), 0xff})
+				continue
+			}
+			br, bg, bbl, _ := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			res.Compared++
+			if absDiff(ar, br) > tol || absDiff(ag, bg) > tol || absDiff(abl, bbl) > tol {
+				res.Different++
+				res.Bounds = res.Bounds.Union(image.Rect(x, y, x+1, y+1))
+				res.Image.Set(x, y, color.RGBA{0xff, 0, 0, 0xff})
+				continue
+			}
+			res.Image.Set(x, y, color.RGBA{uint8(ar >> 9), uint8(ag >> 9), uint8(abl >> 9), 0xff})
+		}
+	}
+	if res.Compared > 0 {
+		res.Ratio = float64(res.Different) / float64(res.Compared)
+	}
+	return res, nil
+}
+
+func included(p image.Point, opts DiffOptions) bool {
+	for _, r := range opts.Ignore {
+		if p.In(r) {
+			return false
+		}
+	}
+	if opts.Mask != nil {
+		if _, _, _, a := opts.Mask.At(p.X, p.Y).RGBA(); a == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func absDiff(a, b uint32) int32 {
+	d := int32(a) - int32(b)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,88 @@
+package vision
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func filled(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestDiff(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	base := filled(40, 30, white)
+	changed := filled(40, 30, white)
+	// a 4x3 block changes colour, one pixel shifts slightly
+	draw.Draw(changed, image.Rect(10, 5, 14, 8), image.NewUniform(color.RGBA{0, 0, 0, 255}), image.Point{}, draw.Src)
+	changed.Set(30, 20, color.RGBA{250, 252, 255, 255})
+
+	tests := []struct {
+		name      string
+		opts      DiffOptions
+		different int
+		bounds    image.Rectangle
+	}{
+		{"exact", DiffOptions{}, 13, image.Rect(10, 5, 31, 21)},
+		{"tolerance", DiffOptions{Tolerance: 8}, 12, image.Rect(10, 5, 14, 8)},
+		{"ignored region", DiffOptions{Tolerance: 8, Ignore: []image.Rectangle{image.Rect(0, 0, 20, 10)}}, 0, image.Rectangle{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Diff(base, changed, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Different != tt.different || r.Bounds != tt.bounds {
+				t.Fatalf("different=%d bounds=%v, want %d %v", r.Different, r.Bounds, tt.different, tt.bounds)
+			}
+			if r.Equal() != (tt.different == 0) {
+				t.Fatalf("Equal() = %v", r.Equal())
+			}
+			if r.Different > 0 && r.Image.RGBAAt(tt.bounds.Min.X, tt.bounds.Min.Y) != (color.RGBA{255, 0, 0, 255}) {
+				t.Fatalf("differing pixel not highlighted: %v", r.Image.RGBAAt(tt.bounds.Min.X, tt.bounds.Min.Y))
+			}
+		})
+	}
+}
+
+func TestDiffRatioAndMask(t *testing.T) {
+	a := filled(10, 10, color.RGBA{0, 0, 0, 255})
+	b := filled(10, 10, color.RGBA{0, 0, 0, 255})
+	draw.Draw(b, image.Rect(0, 0, 10, 5), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	r, err := Diff(a, b, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Compared != 100 || r.Different != 50 || r.Ratio != 0.5 {
+		t.Fatalf("compared=%d different=%d ratio=%v", r.Compared, r.Different, r.Ratio)
+	}
+	// the mask keeps only the bottom half, which is unchanged
+	mask := image.NewAlpha(image.Rect(0, 0, 10, 10))
+	draw.Draw(mask, image.Rect(0, 5, 10, 10), image.Opaque, image.Point{}, draw.Src)
+	r, err = Diff(a, b, DiffOptions{Mask: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Compared != 50 || !r.Equal() {
+		t.Fatalf("masked: compared=%d different=%d", r.Compared, r.Different)
+	}
+}
+
+func TestDiffOffsetBounds(t *testing.T) {
+	// sub-images keep their bounds; pixels are compared by position within each
+	big := filled(50, 50, color.RGBA{9, 9, 9, 255})
+	a := big.SubImage(image.Rect(20, 20, 30, 30))
+	b := filled(10, 10, color.RGBA{9, 9, 9, 255})
+	r, err := Diff(a, b, DiffOptions{})
+	if err != nil || !r.Equal() || r.Compared != 100 {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	if _, err := Diff(a, filled(10, 11, color.Black), DiffOptions{}); err == nil {
+		t.Fatal("size mismatch: want error")
+	}
+}
@@ -0,0 +1,126 @@
+// Package vision provides CPU-only image helpers for screens without a useful
+// layout tree: template matching and pixel diffs on captured frames and screenshots.
+package vision
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Decode decodes a PNG or JPEG frame.
+func Decode(b []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// gray is a float luminance plane.
+type gray struct {
+	w, h int
+	pix  []float64
+}
+
+// toGray converts img (restricted to r) into a luminance plane with origin at r.Min.
+func toGray(img image.Image, r image.Rectangle) *gray {
+	w, h := r.Dx(), r.Dy()
+	g := &gray{w: w, h: h, pix: make([]float64, w*h)}
+	switch src := img.(type) {
+	case *image.YCbCr:
+		// JPEG frames: the Y plane already is luminance
+		for y := 0; y < h; y++ {
+			off := src.YOffset(r.Min.X, r.Min.Y+y)
+			row := src.Y[off : off+w]
+			for x, v := range row {
+				g.pix[y*w+x] = float64(v)
+			}
+		}
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			off := src.PixOffset(r.Min.X, r.Min.Y+y)
+			for x := 0; x < w; x++ {
+				p := src.Pix[off+x*4 : off+x*4+3]
+				g.pix[y*w+x] = lum(uint32(p[0]), uint32(p[1]), uint32(p[2]))
+			}
+		}
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			off := src.PixOffset(r.Min.X, r.Min.Y+y)
+			for x := 0; x < w; x++ {
+				p := src.Pix[off+x*4 : off+x*4+3]
+				g.pix[y*w+x] = lum(uint32(p[0]), uint32(p[1]), uint32(p[2]))
+			}
+		}
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			off := src.PixOffset(r.Min.X, r.Min.Y+y)
+			for x := 0; x < w; x++ {
+				g.pix[y*w+x] = float64(src.Pix[off+x])
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c := color.GrayModel.Convert(img.At(r.Min.X+x, r.Min.Y+y)).(color.Gray)
+				g.pix[y*w+x] = float64(c.Y)
+			}
+		}
+	}
+	return g
+}
+
+func lum(r, g, b uint32) float64 { return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b) }
+
+// downscale shrinks g by an integer factor using box averaging.
+func (g *gray) downscale(f int) *gray {
+	if f <= 1 {
+		return g
+	}
+	w, h := g.w/f, g.h/f
+	out := &gray{w: w, h: h, pix: make([]float64, w*h)}
+	n := float64(f * f)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var s float64
+			for dy := 0; dy < f; dy++ {
+				row := g.pix[(y*f+dy)*g.w+x*f:]
+				for dx := 0; dx < f; dx++ {
+					s += row[dx]
+				}
+			}
+			out.pix[y*w+x] = s / n
+		}
+	}
+	return out
+}
+
+// integral holds summed-area tables of values and squared values.
+type integral struct {
+	w   int
+	sum []float64
+	sq  []float64
+}
+
+func newIntegral(g *gray) *integral {
+	w := g.w + 1
+	it := &integral{w: w, sum: make([]float64, w*(g.h+1)), sq: make([]float64, w*(g.h+1))}
+	for y := 0; y < g.h; y++ {
+		var rs, rq float64
+		for x := 0; x < g.w; x++ {
+			v := g.pix[y*g.w+x]
+			rs += v
+			rq += v * v
+			it.sum[(y+1)*w+x+1] = it.sum[y*w+x+1] + rs
+			it.sq[(y+1)*w+x+1] = it.sq[y*w+x+1] + rq
+		}
+	}
+	return it
+}
+
+// window returns sum and squared sum of the w x h window at (x, y).
+func (it *integral) window(x, y, w, h int) (float64, float64) {
+	a, b := y*it.w+x, y*it.w+x+w
+	c, d := (y+h)*it.w+x, (y+h)*it.w+x+w
+	return it.sum[d] - it.sum[b] - it.sum[c] + it.sum[a], it.sq[d] - it.sq[b] - it.sq[c] + it.sq[a]
+}
@@ -0,0 +1,222 @@
+package vision
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+)
+
+// ErrNotFound is returned when no location reaches the match threshold.
+var ErrNotFound = errors.New("template not found")
+
+// MatchOptions controls template matching.
+type MatchOptions struct {
+	// Threshold is the minimum confidence in [0,1]; default 0.9.
+	Threshold float64
+	// Region restricts the search to part of the source image; zero means the whole image.
+	Region image.Rectangle
+}
+
+// Match is a template location in source image coordinates.
+type Match struct {
+	Rect       image.Rectangle
+	Confidence float64
+}
+
+// Center returns the middle of the matched rectangle, e.g. for tapping it.
+func (m Match) Center() image.Point {
+	return image.Pt((m.Rect.Min.X+m.Rect.Max.X)/2, (m.Rect.Min.Y+m.Rect.Max.Y)/2)
+}
+
+// Find locates the best match of tmpl inside src.
+//
+// Matching uses zero-mean normalized cross-correlation on luminance, so it is
+// robust to uniform brightness changes but ignores hue. Large images are first
+// searched at reduced resolution and the best candidates refined at full size.
+func Find(src, tmpl image.Image, opts MatchOptions) (Match, error) {
+	ms, err := find(src, tmpl, opts, 1)
+	if err != nil {
+		return Match{}, err
+	}
+	if len(ms) == 0 {
+		return Match{}, ErrNotFound
+	}
+	return ms[0], nil
+}
+
+// FindAll returns all non-overlapping matches above the threshold, best first.
+func FindAll(src, tmpl image.Image, opts MatchOptions) ([]Match, error) {
+	return find(src, tmpl, opts, 0)
+}
+
+// minCoarseSide is the smallest template side kept when searching downscaled.
+const minCoarseSide = 12
+
+func find(src, tmpl image.Image, opts MatchOptions, limit int) ([]Match, error) {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = 0.9
+	}
+	area := src.Bounds()
+	if !opts.Region.Empty() {
+		area = area.Intersect(opts.Region)
+	}
+	tb := tmpl.Bounds()
+	if tb.Empty() {
+		return nil, errors.New("empty template")
+	}
+	if tb.Dx() > area.Dx() || tb.Dy() > area.Dy() {
+		return nil, errors.New("template larger than search area")
+	}
+	sg := toGray(src, area)
+	tg := toGray(tmpl, tb)
+
+	f := min(tg.w, tg.h) / minCoarseSide
+	f = max(1, min(f, 8))
+	coarse := newMatcher(sg.downscale(f), tg.downscale(f))
+	// coarse scores are blurrier, so keep candidates a bit below threshold
+	peaks := coarse.peaks(threshold-0.25*float64(f-1)/float64(f), 32)
+
+	full := coarse
+	if f > 1 {
+		full = newMatcher(sg, tg)
+	}
+	var ms []Match
+	for _, p := range peaks {
+		bx, by, best := p.x, p.y, p.score
+		if f > 1 {
+			best = -1
+			for y := max(0, p.y*f-f); y <= min(full.maxY(), p.y*f+f); y++ {
+				for x := max(0, p.x*f-f); x <= min(full.maxX(), p.x*f+f); x++ {
+					if s := full.score(x, y); s > best {
+						bx, by, best = x, y, s
+					}
+				}
+			}
+		}
+		if best < threshold {
+			continue
+		}
+		pt := image.Pt(area.Min.X+bx, area.Min.Y+by)
+		ms = append(ms, Match{Rect: image.Rectangle{Min: pt, Max: pt.Add(image.Pt(tg.w, tg.h))}, Confidence: best})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Confidence > ms[j].Confidence })
+	// non-maximum suppression
+	var out []Match
+	for _, m := range ms {
+		overlap := false
+		for _, o := range out {
+			if m.Center().In(o.Rect) {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			out = append(out, m)
+			if limit > 0 && len(out) == limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+type matcher struct {
+	src   *gray
+	it    *integral
+	tw    int
+	th    int
+	tzero []float64 // template minus its mean
+	tmean float64
+	tnorm float64 // sum of squared tzero
+}
+
+func newMatcher(src, tmpl *gray) *matcher {
+	m := &matcher{src: src, it: newIntegral(src), tw: tmpl.w, th: tmpl.h, tzero: make([]float64, len(tmpl.pix))}
+	for _, v := range tmpl.pix {
+		m.tmean += v
+	}
+	m.tmean /= float64(len(tmpl.pix))
+	for i, v := range tmpl.pix {
+		d := v - m.tmean
+		m.tzero[i] = d
+		m.tnorm += d * d
+	}
+	return m
+}
+
+func (m *matcher) maxX() int { return m.src.w - m.tw }
+func (m *matcher) maxY() int { return m.src.h - m.th }
+
+const flatEps = 1e-6
+
+// score returns the normalized cross-correlation of the template at (x, y).
+func (m *matcher) score(x, y int) float64 {
+	n := float64(m.tw * m.th)
+	s, sq := m.it.window(x, y, m.tw, m.th)
+	variance := sq - s*s/n
+	if m.tnorm < flatEps*n || variance < flatEps*n {
+		// correlation is undefined for flat areas; compare brightness instead
+		if m.tnorm < flatEps*n && variance < flatEps*n {
+			return 1 - math.Abs(s/n-m.tmean)/255
+		}
+		return 0
+	}
+	var dot float64
+	for j := 0; j < m.th; j++ {
+		row := m.src.pix[(y+j)*m.src.w+x : (y+j)*m.src.w+x+m.tw]
+		trow := m.tzero[j*m.tw : (j+1)*m.tw]
+		for i, v := range row {
+			dot += v * trow[i]
+		}
+	}
+	return math.Min(1, dot/math.Sqrt(variance*m.tnorm))
+}
+
+type peak struct {
+	x, y  int
+	score float64
+}
+
+// peaks returns up to limit local maxima of the score map at or above floor.
+func (m *matcher) peaks(floor float64, limit int) []peak {
+	w, h := m.maxX()+1, m.maxY()+1
+	scores := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			scores[y*w+x] = m.score(x, y)
+		}
+	}
+	var ps []peak
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			s := scores[y*w+x]
+			if s < floor || !isLocalMax(scores, w, h, x, y) {
+				continue
+			}
+			ps = append(ps, peak{x, y, s})
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].score > ps[j].score })
+	if len(ps) > limit {
+		ps = ps[:limit]
+	}
+	return ps
+}
+
+func isLocalMax(scores []float64, w, h, x, y int) bool {
+	s := scores[y*w+x]
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= w || ny >= h {
+				continue
+			}
+			if scores[ny*w+nx] > s {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package vision
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// noise returns a w x h image of reproducible random gray levels, a texture
+// with a single best match for any crop of it.
+func noise(w, h int, seed int64) *image.Gray {
+	r := rand.New(rand.NewSource(seed))
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.Intn(256))
+	}
+	return img
+}
+
+// blocks returns a w x h image of random 8x8 tiles, closer to a screen than
+// pixel noise: it survives downscaling.
+func blocks(w, h int, seed int64) *image.Gray {
+	cells := noise((w+7)/8, (h+7)/8, seed)
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, cells.GrayAt(x/8, y/8))
+		}
+	}
+	return img
+}
+
+func crop(img image.Image, r image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	return out
+}
+
+func paste(dst draw.Image, src image.Image, at image.Point) {
+	draw.Draw(dst, src.Bounds().Add(at), src, src.Bounds().Min, draw.Src)
+}
+
+func TestFindExact(t *testing.T) {
+	src := noise(200, 150, 1)
+	want := image.Rect(57, 31, 57+20, 31+16)
+	m, err := Find(src, crop(src, want), MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rect != want || m.Confidence < 0.999 {
+		t.Fatalf("got %v (%.3f), want %v", m.Rect, m.Confidence, want)
+	}
+	if c := m.Center(); c != image.Pt(67, 39) {
+		t.Fatalf("center = %v", c)
+	}
+}
+
+func TestFindLargeTemplateSearchesDownscaled(t *testing.T) {
+	// a 64px template is first matched at 1/5 size, then refined
+	src := blocks(320, 240, 2)
+	want := image.Rect(133, 71, 133+64, 71+64)
+	m, err := Find(src, crop(src, want), MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rect != want {
+		t.Fatalf("got %v (%.3f), want %v", m.Rect, m.Confidence, want)
+	}
+}
+
+func TestFindBrightnessScaledTemplate(t *testing.T) {
+	src := noise(160, 120, 3)
+	want := image.Rect(40, 50, 40+24, 50+24)
+	tmpl := crop(src, want)
+	// the same button captured at a darker screen brightness
+	for i := 0; i < len(tmpl.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			tmpl.Pix[i+c] = uint8(float64(tmpl.Pix[i+c])*0.6 + 20)
+		}
+	}
+	m, err := Find(src, tmpl, MatchOptions{Threshold: 0.95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rect != want {
+		t.Fatalf("got %v (%.3f), want %v", m.Rect, m.Confidence, want)
+	}
+}
+
+func TestFindThresholdMiss(t *testing.T) {
+	src := noise(160, 120, 4)
+	// unrelated texture of the same statistics
+	_, err := Find(src, noise(20, 20, 5), MatchOptions{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFindRegion(t *testing.T) {
+	src := noise(200, 150, 6)
+	at := image.Rect(150, 100, 170, 120)
+	tmpl := crop(src, at)
+	if _, err := Find(src, tmpl, MatchOptions{Region: image.Rect(0, 0, 100, 100)}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("outside region: err = %v, want ErrNotFound", err)
+	}
+	m, err := Find(src, tmpl, MatchOptions{Region: image.Rect(120, 80, 200, 150)})
+	if err != nil || m.Rect != at {
+		t.Fatalf("inside region: got %v, %v; want %v", m.Rect, err, at)
+	}
+}
+
+func TestFindAllOverlapping(t *testing.T) {
+	tmpl := noise(20, 20, 7)
+	src := image.NewGray(image.Rect(0, 0, 200, 120))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.Gray{Y: 128}), image.Point{}, draw.Src)
+	// a copy on its own and two whose rectangles overlap, centers apart
+	spots := []image.Point{{10, 10}, {100, 60}, {115, 75}}
+	for _, p := range spots {
+		paste(src, tmpl, p)
+	}
+	ms, err := FindAll(src, tmpl, MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second copy is partly covered by the third and scores lower
+	got := map[image.Point]bool{}
+	for _, m := range ms {
+		got[m.Rect.Min] = true
+	}
+	if len(ms) != 3 || !got[spots[0]] || !got[spots[1]] || !got[spots[2]] {
+		t.Fatalf("got %v, want matches at %v", ms, spots)
+	}
+	if ms[2].Rect.Min != spots[1] {
+		t.Fatalf("covered copy should rank last: %v", ms)
+	}
+	for i, a := range ms {
+		for _, b := range ms[i+1:] {
+			if b.Center().In(a.Rect) {
+				t.Fatalf("overlapping matches %v and %v", a, b)
+			}
+		}
+	}
+}
+
+func TestFindAllSuppressesNeighbours(t *testing.T) {
+	// a smooth template scores high one pixel off too; only the peak is kept
+	tmpl := image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			tmpl.SetGray(x, y, color.Gray{Y: uint8(x*8 + y*7)})
+		}
+	}
+	src := image.NewGray(image.Rect(0, 0, 80, 60))
+	paste(src, tmpl, image.Pt(30, 20))
+	ms, err := FindAll(src, tmpl, MatchOptions{Threshold: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].Rect.Min != image.Pt(30, 20) {
+		t.Fatalf("got %v, want one match at (30,20)", ms)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	src := noise(20, 20, 8)
+	if _, err := Find(src, noise(30, 10, 9), MatchOptions{}); err == nil {
+		t.Fatal("template larger than source: want error")
+	}
+	if _, err := Find(src, image.NewGray(image.Rect(0, 0, 0, 0)), MatchOptions{}); err == nil {
+		t.Fatal("empty template: want error")
+	}
+}